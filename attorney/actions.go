@@ -5,53 +5,156 @@ package attorney
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/protocol/actions"
 	"github.com/freehandle/breeze/util"
 )
 
-const voidTailSize = 2*crypto.TokenSize + 2*crypto.SignatureSize + 8
+// voidTailSize is the size of the axé signer and signature of a void action
+// followed by the breeze envelope tail. Void data is not length prefixed, so
+// voids are parsed from complete breeze transactions.
+const voidTailSize = crypto.TokenSize + crypto.SignatureSize + envelopeTailSize
 
+// AxeProtocolCode is the protocol code of the public axé network.
 var AxeProtocolCode = [4]byte{1, 0, 0, 0}
 
+// ActionValidator is the view of the axé state against which actions are
+// validated. The Set methods record the mutations of accepted actions.
 type ActionValidator interface {
 	Epoch() uint64
-	HasCaption(crypto.Hash) bool
-	HasMember(crypto.Hash) bool
-	SetNewMember(crypto.Hash, crypto.Hash) bool
-	PowerOfAttorney(crypto.Hash) bool
-	SetNewGrantPower(crypto.Hash) bool
-	SetNewRevokePower(crypto.Hash) bool
+	HasHandle(string) bool
+	HasMember(crypto.Token) bool
+	IsRetired(crypto.Token) bool
+	PowerOfAttorney(token, attorney crypto.Token) bool
+	GrantOf(token, attorney crypto.Token) (AttorneyGrant, bool)
+	HasGrant(token, attorney crypto.Token) bool
+	SetNewMember(token crypto.Token, handle string) bool
+	SetNewHandle(token crypto.Token, handle string) bool
+	SetNewGrantPower(grant AttorneyGrant) bool
+	SetNewRevokePower(token, attorney crypto.Token) bool
+	SetProfile(token crypto.Token, details string)
+	SetRotation(token, newToken crypto.Token) bool
+	SetLeave(token crypto.Token) bool
+	ThresholdOf(token crypto.Token) (ThresholdPolicy, bool)
+	SetThreshold(token crypto.Token, policy ThresholdPolicy)
+	GuardiansOf(token crypto.Token) (ThresholdPolicy, bool)
+	SetGuardians(token crypto.Token, policy ThresholdPolicy)
+	RecoveryOf(token crypto.Token) (Recovery, bool)
+	// RecoveryCancelledAt returns the epoch at which token last cancelled a
+	// recovery of its identity.
+	RecoveryCancelledAt(token crypto.Token) (uint64, bool)
+	SetRecovery(token, newToken crypto.Token) bool
+	SetCancelRecovery(token crypto.Token) bool
+	ProtocolOf(protocol uint32) (RegisteredProtocol, bool)
+	SetProtocol(protocol uint32, registered RegisteredProtocol) bool
+	// AcceptsProtocol checks if voids may target protocol.
+	AcceptsProtocol(protocol uint32) bool
+	// ValidateSubProtocol checks the data of a void with the sub-protocol
+	// registered for its protocol code, if any.
+	ValidateSubProtocol(protocol uint32, author crypto.Token, data []byte) bool
+	// AdmitJoin checks the join limits for one more member joining in the
+	// block, returning the reason of a rejection or Accepted.
+	AdmitJoin() Reason
+	// RequiresInvitation checks if new members must join with an invitation.
+	RequiresInvitation() bool
+	InvitationOf(hash crypto.Hash) (Invitation, bool)
+	SetInvitation(invitation Invitation)
+	SetUsedInvitation(hash crypto.Hash) bool
+	// Cosigned runs validate with the threshold policy of author met, and
+	// IsCosigned checks if it is met for author in the action being validated.
+	Cosigned(author crypto.Token, validate func(ActionValidator) Result) Result
+	IsCosigned(author crypto.Token) bool
+	// Atomically runs validate against a copy of the validator and keeps its
+	// mutations only if the result is accepted.
+	Atomically(validate func(ActionValidator) Result) Result
 }
 
-func GetTokens(data []byte) []crypto.Token {
-	kind := Kind(data)
-	switch kind {
-	case JoinNetworkType:
-		if join := ParseJoinNetwork(data); join != nil {
-			return join.Tokens()
-		}
+// Action is the common interface of every axé action.
+type Action interface {
+	Kind() byte
+	Serialize() []byte
+	Sign(crypto.PrivateKey)
+	// Tokens returns the tokens involved in the action, its author first.
+	Tokens() []crypto.Token
+	Validate(ActionValidator) Result
+	serialize(protocol [4]byte) []byte
+	sign(protocol [4]byte, pk crypto.PrivateKey)
+}
 
-	case UpdateInfoType:
-		if update := ParseUpdateInfo(data); update != nil {
-			return update.Tokens()
-		}
-	case GrantPowerOfAttorneyType:
-		if grant := ParseGrantPowerOfAttorney(data); grant != nil {
-			return grant.Tokens()
-		}
-	case RevokePowerOfAttorneyType:
-		if revoke := ParseRevokePowerOfAttorney(data); revoke != nil {
-			return revoke.Tokens()
+// authorized checks if signer may sign on behalf of author an action allowed
+// by scopes for which allows returns true. Unrestricted grants allow any
+// action.
+func authorized(v ActionValidator, author, signer crypto.Token, allows func(*AttorneyScope) bool) bool {
+	if author.Equal(signer) {
+		return v.PowerOfAttorney(author, signer)
+	}
+	grant, ok := v.GrantOf(author, signer)
+	if !ok {
+		return false
+	}
+	return grant.Scope == nil || allows(grant.Scope)
+}
+
+// decodeFunc decodes an action carried under protocol. Actions nesting other
+// actions parse them with parse.
+type decodeFunc func(data []byte, protocol [4]byte, parse func([]byte) (Action, error)) (Action, error)
+
+func decoder[T Action](decode func([]byte, [4]byte) (T, error)) decodeFunc {
+	return func(data []byte, protocol [4]byte, _ func([]byte) (Action, error)) (Action, error) {
+		action, err := decode(data, protocol)
+		if err != nil {
+			return nil, err
 		}
-	case VoidType:
-		if void := ParseVoid(data); void != nil {
-			return void.Tokens()
+		return action, nil
+	}
+}
+
+// nestingDecoder adapts the decoder of a kind nesting other actions.
+func nestingDecoder[T Action](decode func([]byte, [4]byte, func([]byte) (Action, error)) (T, error)) decodeFunc {
+	return func(data []byte, protocol [4]byte, parse func([]byte) (Action, error)) (Action, error) {
+		action, err := decode(data, protocol, parse)
+		if err != nil {
+			return nil, err
 		}
+		return action, nil
 	}
-	return nil
+}
+
+// decoders maps every axé kind carried under the protocol code of an axé
+// network, and voids carried under any code, to its decoder. New action kinds
+// only need to be registered here.
+var decoders = map[byte]decodeFunc{
+	VoidType:                    anyProtocol(DecodeVoid),
+	JoinNetworkType:             decoder(decodeJoinNetwork),
+	UpdateInfoType:              decoder(decodeUpdateInfo),
+	GrantPowerOfAttorneyType:    decoder(decodeGrantPowerOfAttorney),
+	RevokePowerOfAttorneyType:   decoder(decodeRevokePowerOfAttorney),
+	KeyExchangeType:             decoder(decodeKeyExchange),
+	ChangeHandleType:            decoder(decodeChangeHandle),
+	RotateKeyType:               decoder(decodeRotateKey),
+	LeaveNetworkType:            decoder(decodeLeaveNetwork),
+	DelegatePowerOfAttorneyType: decoder(decodeDelegatePowerOfAttorney),
+	SetThresholdType:            decoder(decodeSetThreshold),
+	MultiVoidType:               decoder(decodeMultiVoid),
+	SetGuardiansType:            decoder(decodeSetGuardians),
+	RequestRecoveryType:         decoder(decodeRequestRecovery),
+	CancelRecoveryType:          decoder(decodeCancelRecovery),
+	RegisterProtocolType:        decoder(decodeRegisterProtocol),
+	InviteType:                  decoder(decodeInvite),
+	JoinWithInviteType:          decoder(decodeJoinWithInvite),
+	BundleType:                  nestingDecoder(decodeBundle),
+	CosignedType:                nestingDecoder(decodeCosigned),
+}
+
+// ParseAction parses any action of the public axé network. Void actions may
+// carry any protocol code, other kinds must carry the axé protocol code.
+func ParseAction(data []byte) (Action, error) {
+	return DefaultCodec.Parse(data)
+}
+
+func GetTokens(data []byte) []crypto.Token {
+	return DefaultCodec.GetTokens(data)
 }
 
 const (
@@ -60,21 +163,31 @@ const (
 	UpdateInfoType
 	GrantPowerOfAttorneyType
 	RevokePowerOfAttorneyType
+	KeyExchangeType
+	ChangeHandleType
+	RotateKeyType
+	LeaveNetworkType
+	DelegatePowerOfAttorneyType
+	SetThresholdType
+	MultiVoidType
+	SetGuardiansType
+	RequestRecoveryType
+	CancelRecoveryType
+	BundleType
+	RegisterProtocolType
+	InviteType
+	JoinWithInviteType
+	CosignedType
 	Invalid
 )
 
 func Kind(data []byte) byte {
-	if len(data) < 14 {
-		return Invalid
-	}
-	if data[0] != 0 || data[1] != actions.IVoid || data[10] != 1 {
-		return Invalid
-	}
-	return data[14]
+	return DefaultCodec.Kind(data)
 }
 
 type JoinNetwork struct {
 	Epoch     uint64
+	Version   byte
 	Author    crypto.Token
 	Handle    string
 	Details   string
@@ -85,33 +198,47 @@ func (j *JoinNetwork) Tokens() []crypto.Token {
 	return []crypto.Token{j.Author}
 }
 
-func (j *JoinNetwork) Validate(v ActionValidator) bool {
-	captionHash := crypto.Hasher([]byte(j.Handle))
-	if v.HasCaption(captionHash) {
-		return false
+func (j *JoinNetwork) Validate(v ActionValidator) Result {
+	if v.RequiresInvitation() {
+		return reject(JoinNetworkType, InvitationRequired, crypto.HashToken(j.Author))
 	}
-	authorHash := crypto.Hasher(j.Author[:])
-	if v.HasMember(authorHash) {
-		return false
+	return join(v, JoinNetworkType, j.Author, j.Handle, j.Details)
+}
+
+// join validates the membership of author with handle and the details of its
+// profile set at epoch, for join actions of the given kind.
+func join(v ActionValidator, kind byte, author crypto.Token, handle string, details string) Result {
+	memberHash := crypto.HashToken(author)
+	captionHash := crypto.Hasher([]byte(handle))
+	if len(handle) > MaxHandleSize {
+		return reject(kind, HandleTooLong, captionHash)
 	}
-	if !json.Valid([]byte(j.Details)) {
-		return false
+	if v.HasHandle(handle) {
+		return reject(kind, HandleTaken, captionHash)
+	}
+	if v.HasMember(author) {
+		return reject(kind, AlreadyMember, memberHash)
+	}
+	if v.IsRetired(author) {
+		return reject(kind, RetiredToken, memberHash)
 	}
-	return v.SetNewMember(authorHash, captionHash)
+	if reason := v.AdmitJoin(); reason != Accepted {
+		return reject(kind, reason, memberHash)
+	}
+	if !v.SetNewMember(author, handle) {
+		// the author left the network within the same block
+		return reject(kind, PendingLeave, memberHash)
+	}
+	v.SetProfile(author, details)
+	return accept(kind, memberHash, captionHash)
 }
 
 func (j *JoinNetwork) Kind() byte {
 	return JoinNetworkType
 }
 
-func (j *JoinNetwork) serializeToSign() []byte {
-	bytes := []byte{0, actions.IVoid} // breeze (version 0) void action
-	util.PutUint64(j.Epoch, &bytes)
-	util.PutByte(1, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(JoinNetworkType, &bytes)
+func (j *JoinNetwork) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, j.Epoch, JoinNetworkType, j.Version)
 	util.PutToken(j.Author, &bytes)
 	util.PutString(j.Handle, &bytes)
 	util.PutString(j.Details, &bytes)
@@ -119,51 +246,63 @@ func (j *JoinNetwork) serializeToSign() []byte {
 }
 
 func (j *JoinNetwork) Serialize() []byte {
-	bytes := j.serializeToSign()
+	return j.serialize(AxeProtocolCode)
+}
+
+func (j *JoinNetwork) serialize(protocol [4]byte) []byte {
+	bytes := j.serializeToSign(protocol)
 	util.PutSignature(j.Signature, &bytes)
 	return bytes
 }
 
 func (j *JoinNetwork) Sign(key crypto.PrivateKey) {
-	bytes := j.serializeToSign()
+	j.sign(AxeProtocolCode, key)
+}
+
+func (j *JoinNetwork) sign(protocol [4]byte, key crypto.PrivateKey) {
+	bytes := j.serializeToSign(protocol)
 	j.Signature = key.Sign(bytes)
 }
 
 func ParseJoinNetwork(data []byte) *JoinNetwork {
-	if data[0] != 0 || data[1] != actions.IVoid || len(data) < 14 {
-		fmt.Println(3)
+	join, err := DecodeJoinNetwork(data)
+	if err != nil {
 		return nil
 	}
+	return join
+}
+
+// DecodeJoinNetwork is like ParseJoinNetwork but returns the reason the
+// action could not be parsed.
+func DecodeJoinNetwork(data []byte) (*JoinNetwork, error) {
+	return decodeJoinNetwork(data, AxeProtocolCode)
+}
+
+func decodeJoinNetwork(data []byte, protocol [4]byte) (*JoinNetwork, error) {
+	var err error
 	join := JoinNetwork{}
-	position := 2
-	join.Epoch, position = util.ParseUint64(data, position)
-	// check if it is pure axe protocol
-	if data[position] != 1 || data[position+1] != 0 || data[position+2] != 0 || data[position+3] != 0 {
-		return nil
-	}
-	if data[position+4] != JoinNetworkType {
-		return nil
+	position := 0
+	if join.Epoch, join.Version, position, err = parseHeader(data, protocol, JoinNetworkType); err != nil {
+		return nil, err
 	}
-	position = position + 5
 	join.Author, position = util.ParseToken(data, position)
 	join.Handle, position = util.ParseString(data, position)
 	join.Details, position = util.ParseString(data, position)
-	if len(join.Details) > 0 && !json.Valid([]byte(join.Details)) {
-		return nil
-	}
-	hashPosition := position
-	join.Signature, position = util.ParseSignature(data, position)
 	if position > len(data) {
-		return nil
+		return nil, ErrTruncated
 	}
-	if !join.Author.Verify(data[0:hashPosition], join.Signature) {
-		return nil
+	if len(join.Details) > 0 && !json.Valid([]byte(join.Details)) {
+		return nil, ErrInvalidJSON
+	}
+	if join.Signature, err = parseTail(data, position, join.Author); err != nil {
+		return nil, err
 	}
-	return &join
+	return &join, nil
 }
 
 type UpdateInfo struct {
 	Epoch     uint64
+	Version   byte
 	Author    crypto.Token
 	Details   string
 	Signer    crypto.Token
@@ -178,17 +317,19 @@ func (u *UpdateInfo) Tokens() []crypto.Token {
 	}
 }
 
-func (u *UpdateInfo) Validate(v ActionValidator) bool {
-	if !v.HasMember(crypto.HashToken(u.Author)) {
-		return false
+func (u *UpdateInfo) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(u.Author)
+	if u.Epoch > v.Epoch() {
+		return reject(UpdateInfoType, FutureEpoch)
 	}
-	if !u.Signer.Equal(u.Author) {
-		hash := crypto.Hasher(append(u.Author[:], u.Signer[:]...))
-		if !v.PowerOfAttorney(hash) {
-			return false
-		}
+	if !v.HasMember(u.Author) {
+		return reject(UpdateInfoType, NotMember, memberHash)
 	}
-	return json.Valid([]byte(u.Details))
+	if !authorized(v, u.Author, u.Signer, (*AttorneyScope).AllowsProfile) {
+		return reject(UpdateInfoType, NoPowerOfAttorney, attorneyHash(u.Author, u.Signer))
+	}
+	v.SetProfile(u.Author, u.Details)
+	return accept(UpdateInfoType, memberHash)
 }
 
 func (u *UpdateInfo) Kind() byte {
@@ -196,19 +337,17 @@ func (u *UpdateInfo) Kind() byte {
 }
 
 func (u *UpdateInfo) Serialize() []byte {
-	bytes := u.serializeToSign()
+	return u.serialize(AxeProtocolCode)
+}
+
+func (u *UpdateInfo) serialize(protocol [4]byte) []byte {
+	bytes := u.serializeToSign(protocol)
 	util.PutSignature(u.Signature, &bytes)
 	return bytes
 }
 
-func (u *UpdateInfo) serializeToSign() []byte {
-	bytes := []byte{0, actions.IVoid}
-	util.PutUint64(u.Epoch, &bytes)
-	util.PutByte(1, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(UpdateInfoType, &bytes)
+func (u *UpdateInfo) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, u.Epoch, UpdateInfoType, u.Version)
 	util.PutToken(u.Author, &bytes)
 	util.PutString(u.Details, &bytes)
 	util.PutToken(u.Signer, &bytes)
@@ -216,47 +355,67 @@ func (u *UpdateInfo) serializeToSign() []byte {
 }
 
 func (u *UpdateInfo) Sign(pk crypto.PrivateKey) {
-	bytes := u.serializeToSign()
+	u.sign(AxeProtocolCode, pk)
+}
+
+func (u *UpdateInfo) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := u.serializeToSign(protocol)
 	u.Signature = pk.Sign(bytes)
 }
 
 func ParseUpdateInfo(data []byte) *UpdateInfo {
-	if data[0] != 0 || data[1] != actions.IVoid || len(data) < 14 {
+	update, err := DecodeUpdateInfo(data)
+	if err != nil {
 		return nil
 	}
+	return update
+}
+
+// DecodeUpdateInfo is like ParseUpdateInfo but returns the reason the action
+// could not be parsed.
+func DecodeUpdateInfo(data []byte) (*UpdateInfo, error) {
+	return decodeUpdateInfo(data, AxeProtocolCode)
+}
+
+func decodeUpdateInfo(data []byte, protocol [4]byte) (*UpdateInfo, error) {
+	var err error
 	update := UpdateInfo{}
-	position := 2
-	update.Epoch, position = util.ParseUint64(data, position)
-	// check if it is pure axe protocol
-	if data[position] != 1 || data[position+1] != 0 || data[position+2] != 0 || data[position+3] != 0 {
-		return nil
+	position := 0
+	if update.Epoch, update.Version, position, err = parseHeader(data, protocol, UpdateInfoType); err != nil {
+		return nil, err
 	}
-	if data[position+4] != UpdateInfoType {
-		return nil
-	}
-	position = position + 5
 	update.Author, position = util.ParseToken(data, position)
 	update.Details, position = util.ParseString(data, position)
+	if position > len(data) {
+		return nil, ErrTruncated
+	}
 	if !json.Valid([]byte(update.Details)) {
-		return nil
+		return nil, ErrInvalidJSON
 	}
 	update.Signer, position = util.ParseToken(data, position)
-	hashPosition := position
-	update.Signature, position = util.ParseSignature(data, position)
-	if position > len(data) {
-		return nil
+	if update.Signature, err = parseTail(data, position, update.Signer); err != nil {
+		return nil, err
 	}
-	if !update.Author.Verify(data[0:hashPosition], update.Signature) {
-		return nil
-	}
-	return &update
+	return &update, nil
 }
 
+// GrantPowerOfAttorney lets Attorney sign axé actions on behalf of Author.
+// A non zero Expiry is the last epoch at which the grant is valid. A non nil
+// Scope restricts the actions the attorney may sign. Depth is how many levels
+// of onward delegation the attorney may grant, zero forbidding delegation.
+// Expiry, Scope and Depth are only serialized from Version1 on, so Version0
+// grants never expire, are unrestricted and cannot be delegated. A Version0
+// grant with any of them set is not serialized nor signed, rather than
+// silently granting full power.
 type GrantPowerOfAttorney struct {
 	Epoch       uint64
+	Version     byte
 	Author      crypto.Token
 	Attorney    crypto.Token
 	Fingerprint []byte
+	Expiry      uint64
+	Scope       *AttorneyScope
+	Depth       byte
 	Signature   crypto.Signature
 }
 
@@ -264,77 +423,121 @@ func (g *GrantPowerOfAttorney) Tokens() []crypto.Token {
 	return []crypto.Token{g.Author, g.Attorney}
 }
 
-func (g *GrantPowerOfAttorney) Validate(v ActionValidator) bool {
-	if !v.HasMember(crypto.HashToken(g.Author)) {
-		return false
+func (g *GrantPowerOfAttorney) Validate(v ActionValidator) Result {
+	hash := attorneyHash(g.Author, g.Attorney)
+	if !v.HasMember(g.Author) {
+		return reject(GrantPowerOfAttorneyType, NotMember, crypto.HashToken(g.Author))
 	}
-	hash := crypto.Hasher(append(g.Author[:], g.Attorney[:]...))
-	if v.PowerOfAttorney(hash) {
-		return false
+	if !thresholdApproved(v, g.Author) {
+		return reject(GrantPowerOfAttorneyType, ThresholdNotMet, crypto.HashToken(g.Author))
 	}
-	return v.SetNewGrantPower(hash)
+	if _, ok := v.GrantOf(g.Author, g.Attorney); ok || g.Author.Equal(g.Attorney) {
+		return reject(GrantPowerOfAttorneyType, AlreadyAttorney, hash)
+	}
+	if g.Expiry != 0 && g.Expiry < v.Epoch() {
+		return reject(GrantPowerOfAttorneyType, Expired, hash)
+	}
+	if g.Depth > MaxDelegationDepth {
+		return reject(GrantPowerOfAttorneyType, DelegationTooDeep, hash)
+	}
+	v.SetNewGrantPower(AttorneyGrant{Author: g.Author, Attorney: g.Attorney, Expiry: g.Expiry, Scope: g.Scope, Depth: g.Depth})
+	return accept(GrantPowerOfAttorneyType, hash)
 }
 
 func (g *GrantPowerOfAttorney) Kind() byte {
 	return GrantPowerOfAttorneyType
 }
 
+// restricted checks if the grant has an expiry, a scope or a delegation
+// depth, which need Version1 or later.
+func (g *GrantPowerOfAttorney) restricted() bool {
+	return g.Expiry != 0 || g.Scope != nil || g.Depth != 0
+}
+
+// Serialize returns nil for Version0 grants with Expiry, Scope or Depth set.
 func (g *GrantPowerOfAttorney) Serialize() []byte {
-	bytes := g.serializeToSign()
+	return g.serialize(AxeProtocolCode)
+}
+
+func (g *GrantPowerOfAttorney) serialize(protocol [4]byte) []byte {
+	bytes := g.serializeToSign(protocol)
+	if bytes == nil {
+		return nil
+	}
 	util.PutSignature(g.Signature, &bytes)
 	return bytes
 }
 
-func (g *GrantPowerOfAttorney) serializeToSign() []byte {
-	bytes := []byte{0, actions.IVoid}
-	util.PutUint64(g.Epoch, &bytes)
-	util.PutByte(1, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(GrantPowerOfAttorneyType, &bytes)
+// serializeToSign returns nil if the grant cannot be encoded in its version.
+func (g *GrantPowerOfAttorney) serializeToSign(protocol [4]byte) []byte {
+	if g.Version == Version0 && g.restricted() {
+		return nil
+	}
+	bytes := putHeader(protocol, g.Epoch, GrantPowerOfAttorneyType, g.Version)
 	util.PutToken(g.Author, &bytes)
 	util.PutByteArray(g.Fingerprint, &bytes)
 	util.PutToken(g.Attorney, &bytes)
+	if g.Version >= Version1 {
+		util.PutUint64(g.Expiry, &bytes)
+		putScope(g.Scope, &bytes)
+		util.PutByte(g.Depth, &bytes)
+	}
 	return bytes
 }
 
 func (g *GrantPowerOfAttorney) Sign(pk crypto.PrivateKey) {
-	bytes := g.serializeToSign()
+	g.sign(AxeProtocolCode, pk)
+}
+
+func (g *GrantPowerOfAttorney) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := g.serializeToSign(protocol)
+	if bytes == nil {
+		return
+	}
 	g.Signature = pk.Sign(bytes)
 }
 
 func ParseGrantPowerOfAttorney(data []byte) *GrantPowerOfAttorney {
-	if data[0] != 0 || data[1] != actions.IVoid || len(data) < 14 {
+	grant, err := DecodeGrantPowerOfAttorney(data)
+	if err != nil {
 		return nil
 	}
+	return grant
+}
+
+// DecodeGrantPowerOfAttorney is like ParseGrantPowerOfAttorney but returns
+// the reason the action could not be parsed.
+func DecodeGrantPowerOfAttorney(data []byte) (*GrantPowerOfAttorney, error) {
+	return decodeGrantPowerOfAttorney(data, AxeProtocolCode)
+}
+
+func decodeGrantPowerOfAttorney(data []byte, protocol [4]byte) (*GrantPowerOfAttorney, error) {
+	var err error
 	grant := GrantPowerOfAttorney{}
-	position := 2
-	grant.Epoch, position = util.ParseUint64(data, position)
-	// check if it is pure axe protocol
-	if data[position] != 1 || data[position+1] != 0 || data[position+2] != 0 || data[position+3] != 0 {
-		return nil
+	position := 0
+	if grant.Epoch, grant.Version, position, err = parseHeader(data, protocol, GrantPowerOfAttorneyType); err != nil {
+		return nil, err
 	}
-	if data[position+4] != GrantPowerOfAttorneyType {
-		return nil
-	}
-	position = position + 5
 	grant.Author, position = util.ParseToken(data, position)
 	grant.Fingerprint, position = util.ParseByteArray(data, position)
 	grant.Attorney, position = util.ParseToken(data, position)
-	hashPosition := position
-	grant.Signature, position = util.ParseSignature(data, position)
-	if position > len(data) {
-		return nil
+	if grant.Version >= Version1 {
+		grant.Expiry, position = util.ParseUint64(data, position)
+		grant.Scope, position = parseScope(data, position)
+		if position >= len(data) {
+			return nil, ErrTruncated
+		}
+		grant.Depth, position = data[position], position+1
 	}
-	if !grant.Author.Verify(data[0:hashPosition], grant.Signature) {
-		return nil
+	if grant.Signature, err = parseTail(data, position, grant.Author); err != nil {
+		return nil, err
 	}
-	return &grant
+	return &grant, nil
 }
 
 type RevokePowerOfAttorney struct {
 	Epoch     uint64
+	Version   byte
 	Author    crypto.Token
 	Attorney  crypto.Token
 	Signature crypto.Signature
@@ -344,71 +547,75 @@ func (r *RevokePowerOfAttorney) Tokens() []crypto.Token {
 	return []crypto.Token{r.Author, r.Attorney}
 }
 
-func (r *RevokePowerOfAttorney) Validate(v ActionValidator) bool {
-	if !v.HasMember(crypto.HashToken(r.Author)) {
-		return false
+func (r *RevokePowerOfAttorney) Validate(v ActionValidator) Result {
+	hash := attorneyHash(r.Author, r.Attorney)
+	if !v.HasMember(r.Author) {
+		return reject(RevokePowerOfAttorneyType, NotMember, crypto.HashToken(r.Author))
 	}
-	hash := crypto.Hasher(append(r.Author[:], r.Attorney[:]...))
-	if !v.PowerOfAttorney(hash) {
-		return false
+	if !v.HasGrant(r.Author, r.Attorney) {
+		return reject(RevokePowerOfAttorneyType, NoPowerOfAttorney, hash)
 	}
-	return v.SetNewRevokePower(hash)
+	v.SetNewRevokePower(r.Author, r.Attorney)
+	return accept(RevokePowerOfAttorneyType, hash)
 }
 
 func (r *RevokePowerOfAttorney) Kind() byte {
 	return RevokePowerOfAttorneyType
 }
 
-func (r *RevokePowerOfAttorney) serializeToSign() []byte {
-	bytes := []byte{0, actions.IVoid}
-	util.PutUint64(r.Epoch, &bytes)
-	util.PutByte(1, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(0, &bytes)
-	util.PutByte(RevokePowerOfAttorneyType, &bytes)
+func (r *RevokePowerOfAttorney) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, r.Epoch, RevokePowerOfAttorneyType, r.Version)
 	util.PutToken(r.Author, &bytes)
 	util.PutToken(r.Attorney, &bytes)
 	return bytes
 }
 
 func (r *RevokePowerOfAttorney) Serialize() []byte {
-	bytes := r.serializeToSign()
+	return r.serialize(AxeProtocolCode)
+}
+
+func (r *RevokePowerOfAttorney) serialize(protocol [4]byte) []byte {
+	bytes := r.serializeToSign(protocol)
 	util.PutSignature(r.Signature, &bytes)
 	return bytes
 }
 
 func (r *RevokePowerOfAttorney) Sign(pk crypto.PrivateKey) {
-	bytes := r.serializeToSign()
+	r.sign(AxeProtocolCode, pk)
+}
+
+func (r *RevokePowerOfAttorney) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := r.serializeToSign(protocol)
 	r.Signature = pk.Sign(bytes)
 }
 
 func ParseRevokePowerOfAttorney(data []byte) *RevokePowerOfAttorney {
-	if data[0] != 0 || data[1] != actions.IVoid || len(data) < 14 {
+	revoke, err := DecodeRevokePowerOfAttorney(data)
+	if err != nil {
 		return nil
 	}
+	return revoke
+}
+
+// DecodeRevokePowerOfAttorney is like ParseRevokePowerOfAttorney but returns
+// the reason the action could not be parsed.
+func DecodeRevokePowerOfAttorney(data []byte) (*RevokePowerOfAttorney, error) {
+	return decodeRevokePowerOfAttorney(data, AxeProtocolCode)
+}
+
+func decodeRevokePowerOfAttorney(data []byte, protocol [4]byte) (*RevokePowerOfAttorney, error) {
+	var err error
 	revoke := RevokePowerOfAttorney{}
-	position := 2
-	revoke.Epoch, position = util.ParseUint64(data, position)
-	// check if it is pure axe protocol
-	if data[position] != 1 || data[position+1] != 0 || data[position+2] != 0 || data[position+3] != 0 {
-		return nil
-	}
-	if data[position+4] != RevokePowerOfAttorneyType {
-		return nil
+	position := 0
+	if revoke.Epoch, revoke.Version, position, err = parseHeader(data, protocol, RevokePowerOfAttorneyType); err != nil {
+		return nil, err
 	}
-	position = position + 5
 	revoke.Author, position = util.ParseToken(data, position)
 	revoke.Attorney, position = util.ParseToken(data, position)
-	hashPosition := position
-	revoke.Signature, position = util.ParseSignature(data, position)
-	if position > len(data) {
-		return nil
-	}
-	if !revoke.Author.Verify(data[0:hashPosition], revoke.Signature) {
-		return nil
+	if revoke.Signature, err = parseTail(data, position, revoke.Author); err != nil {
+		return nil, err
 	}
-	return &revoke
+	return &revoke, nil
 }
 
 type Void struct {
@@ -428,20 +635,30 @@ func (g *Void) Tokens() []crypto.Token {
 	}
 }
 
-func (void *Void) Validate(v ActionValidator) bool {
+func (void *Void) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(void.Author)
 	if void.Epoch > v.Epoch() {
-		return false
+		return reject(VoidType, FutureEpoch)
 	}
-	if !v.HasMember(crypto.HashToken(void.Author)) {
-		return false
+	if !v.HasMember(void.Author) {
+		return reject(VoidType, NotMember, memberHash)
 	}
-	if !void.Signer.Equal(void.Author) {
-		hash := crypto.Hasher(append(void.Author[:], void.Signer[:]...))
-		if !v.PowerOfAttorney(hash) {
-			return false
-		}
+	if _, ok := v.ThresholdOf(void.Author); ok {
+		return reject(VoidType, ThresholdNotMet, memberHash)
+	}
+	if !v.AcceptsProtocol(void.Protocol) {
+		return reject(VoidType, UnregisteredProtocol, memberHash)
+	}
+	allows := func(scope *AttorneyScope) bool {
+		return scope.AllowsProtocol(void.Protocol)
 	}
-	return true
+	if !authorized(v, void.Author, void.Signer, allows) {
+		return reject(VoidType, NoPowerOfAttorney, attorneyHash(void.Author, void.Signer))
+	}
+	if !v.ValidateSubProtocol(void.Protocol, void.Author, void.Data) {
+		return reject(VoidType, SubProtocolRejected, memberHash)
+	}
+	return accept(VoidType, memberHash)
 }
 
 func (v *Void) Kind() byte {
@@ -470,54 +687,467 @@ func (v *Void) Sign(pk crypto.PrivateKey) {
 	v.Signature = pk.Sign(bytes)
 }
 
+// serialize ignores the axé protocol code, voids carry their own.
+func (v *Void) serialize(protocol [4]byte) []byte {
+	return v.Serialize()
+}
+
+func (v *Void) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	v.Sign(pk)
+}
+
 func ParseVoid(data []byte) *Void {
-	if data[0] != 0 || data[1] != actions.IVoid || len(data) < 14 {
+	void, err := DecodeVoid(data)
+	if err != nil {
 		return nil
 	}
+	return void
+}
+
+// DecodeVoid is like ParseVoid but returns the reason the action could not be
+// parsed. Void actions may carry any protocol code.
+func DecodeVoid(data []byte) (*Void, error) {
+	if len(data) < headerSize {
+		return nil, ErrTruncated
+	}
+	if data[0] != 0 {
+		return nil, ErrBadVersion
+	}
+	if data[1] != actions.IVoid {
+		return nil, ErrWrongKind
+	}
 	void := Void{}
 	position := 2
 	void.Epoch, position = util.ParseUint64(data, position)
 	void.Protocol, position = util.ParseUint32(data, position)
 	if data[position] != VoidType {
-		return nil
+		return nil, ErrWrongKind
 	}
 	position = position + 1
 	void.Author, position = util.ParseToken(data, position)
 	if len(data)-voidTailSize < position {
-		return nil
+		return nil, ErrTruncated
 	}
 	void.Data = data[position : len(data)-voidTailSize]
 	position = len(data) - voidTailSize
 	void.Signer, position = util.ParseToken(data, position)
-	hashPosition := position
-	void.Signature, position = util.ParseSignature(data, position)
-	if position > len(data) {
-		return nil
-	}
-	if !void.Signer.Verify(data[0:hashPosition], void.Signature) {
-		return nil
+	var err error
+	if void.Signature, err = parseTail(data, position, void.Signer); err != nil {
+		return nil, err
 	}
-	return &void
+	return &void, nil
 }
 
+// KeyExchange lets a member publish a secret encrypted to another member, so
+// that both can derive a shared key for encrypted payloads of other protocols.
+// Ephemeral is the ephemeral public key used to encrypt Secret. The action is
+// signed by Attorney, which is either the Author or one of its attorneys.
 type KeyExchange struct {
 	Epoch     uint64
+	Version   byte
 	Author    crypto.Token
 	To        crypto.Token
 	Ephemeral crypto.Token
 	Secret    []byte
 	Attorney  crypto.Token
+	Signature crypto.Signature
 }
 
-// iisAxeNonVoid checks if a byte array has the header of an axé action different from
+func (k *KeyExchange) Tokens() []crypto.Token {
+	tokens := []crypto.Token{k.Author}
+	if !k.To.Equal(k.Author) {
+		tokens = append(tokens, k.To)
+	}
+	if !k.Attorney.Equal(k.Author) && !k.Attorney.Equal(k.To) {
+		tokens = append(tokens, k.Attorney)
+	}
+	return tokens
+}
+
+func (k *KeyExchange) Validate(v ActionValidator) Result {
+	authorHash := crypto.HashToken(k.Author)
+	toHash := crypto.HashToken(k.To)
+	if !v.HasMember(k.Author) {
+		return reject(KeyExchangeType, NotMember, authorHash)
+	}
+	if !v.HasMember(k.To) {
+		return reject(KeyExchangeType, NotMember, toHash)
+	}
+	if !v.PowerOfAttorney(k.Author, k.Attorney) {
+		return reject(KeyExchangeType, NoPowerOfAttorney, attorneyHash(k.Author, k.Attorney))
+	}
+	return accept(KeyExchangeType, authorHash, toHash)
+}
+
+func (k *KeyExchange) Kind() byte {
+	return KeyExchangeType
+}
+
+func (k *KeyExchange) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, k.Epoch, KeyExchangeType, k.Version)
+	util.PutToken(k.Author, &bytes)
+	util.PutToken(k.To, &bytes)
+	util.PutToken(k.Ephemeral, &bytes)
+	util.PutByteArray(k.Secret, &bytes)
+	util.PutToken(k.Attorney, &bytes)
+	return bytes
+}
+
+func (k *KeyExchange) Serialize() []byte {
+	return k.serialize(AxeProtocolCode)
+}
+
+func (k *KeyExchange) serialize(protocol [4]byte) []byte {
+	bytes := k.serializeToSign(protocol)
+	util.PutSignature(k.Signature, &bytes)
+	return bytes
+}
+
+func (k *KeyExchange) Sign(pk crypto.PrivateKey) {
+	k.sign(AxeProtocolCode, pk)
+}
+
+func (k *KeyExchange) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := k.serializeToSign(protocol)
+	k.Signature = pk.Sign(bytes)
+}
+
+func ParseKeyExchange(data []byte) *KeyExchange {
+	exchange, err := DecodeKeyExchange(data)
+	if err != nil {
+		return nil
+	}
+	return exchange
+}
+
+// DecodeKeyExchange is like ParseKeyExchange but returns the reason the
+// action could not be parsed.
+func DecodeKeyExchange(data []byte) (*KeyExchange, error) {
+	return decodeKeyExchange(data, AxeProtocolCode)
+}
+
+func decodeKeyExchange(data []byte, protocol [4]byte) (*KeyExchange, error) {
+	var err error
+	exchange := KeyExchange{}
+	position := 0
+	if exchange.Epoch, exchange.Version, position, err = parseHeader(data, protocol, KeyExchangeType); err != nil {
+		return nil, err
+	}
+	exchange.Author, position = util.ParseToken(data, position)
+	exchange.To, position = util.ParseToken(data, position)
+	exchange.Ephemeral, position = util.ParseToken(data, position)
+	exchange.Secret, position = util.ParseByteArray(data, position)
+	exchange.Attorney, position = util.ParseToken(data, position)
+	if exchange.Signature, err = parseTail(data, position, exchange.Attorney); err != nil {
+		return nil, err
+	}
+	return &exchange, nil
+}
+
+// ChangeHandle renames a member. The old handle is released and the new one
+// claimed atomically. It is signed by the Author or one of its attorneys.
+type ChangeHandle struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Handle    string
+	Signer    crypto.Token
+	Signature crypto.Signature
+}
+
+func (c *ChangeHandle) Tokens() []crypto.Token {
+	if c.Signer.Equal(c.Author) {
+		return []crypto.Token{c.Author}
+	} else {
+		return []crypto.Token{c.Author, c.Signer}
+	}
+}
+
+func (c *ChangeHandle) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(c.Author)
+	captionHash := crypto.Hasher([]byte(c.Handle))
+	if !v.HasMember(c.Author) {
+		return reject(ChangeHandleType, NotMember, memberHash)
+	}
+	if !authorized(v, c.Author, c.Signer, (*AttorneyScope).AllowsProfile) {
+		return reject(ChangeHandleType, NoPowerOfAttorney, attorneyHash(c.Author, c.Signer))
+	}
+	if len(c.Handle) > MaxHandleSize {
+		return reject(ChangeHandleType, HandleTooLong, captionHash)
+	}
+	if v.HasHandle(c.Handle) {
+		return reject(ChangeHandleType, HandleTaken, captionHash)
+	}
+	if !v.SetNewHandle(c.Author, c.Handle) {
+		return reject(ChangeHandleType, HandleTaken, captionHash)
+	}
+	return accept(ChangeHandleType, memberHash, captionHash)
+}
+
+func (c *ChangeHandle) Kind() byte {
+	return ChangeHandleType
+}
+
+func (c *ChangeHandle) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, c.Epoch, ChangeHandleType, c.Version)
+	util.PutToken(c.Author, &bytes)
+	util.PutString(c.Handle, &bytes)
+	util.PutToken(c.Signer, &bytes)
+	return bytes
+}
+
+func (c *ChangeHandle) Serialize() []byte {
+	return c.serialize(AxeProtocolCode)
+}
+
+func (c *ChangeHandle) serialize(protocol [4]byte) []byte {
+	bytes := c.serializeToSign(protocol)
+	util.PutSignature(c.Signature, &bytes)
+	return bytes
+}
+
+func (c *ChangeHandle) Sign(pk crypto.PrivateKey) {
+	c.sign(AxeProtocolCode, pk)
+}
+
+func (c *ChangeHandle) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := c.serializeToSign(protocol)
+	c.Signature = pk.Sign(bytes)
+}
+
+func ParseChangeHandle(data []byte) *ChangeHandle {
+	change, err := DecodeChangeHandle(data)
+	if err != nil {
+		return nil
+	}
+	return change
+}
+
+// DecodeChangeHandle is like ParseChangeHandle but returns the reason the
+// action could not be parsed.
+func DecodeChangeHandle(data []byte) (*ChangeHandle, error) {
+	return decodeChangeHandle(data, AxeProtocolCode)
+}
+
+func decodeChangeHandle(data []byte, protocol [4]byte) (*ChangeHandle, error) {
+	var err error
+	change := ChangeHandle{}
+	position := 0
+	if change.Epoch, change.Version, position, err = parseHeader(data, protocol, ChangeHandleType); err != nil {
+		return nil, err
+	}
+	change.Author, position = util.ParseToken(data, position)
+	change.Handle, position = util.ParseString(data, position)
+	change.Signer, position = util.ParseToken(data, position)
+	if change.Signature, err = parseTail(data, position, change.Signer); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// RotateKey migrates the identity of a member to a new token. Membership,
+// handle, profile and granted powers of attorney move to NewToken and Author
+// is retired. It is signed by Author and countersigned by NewToken.
+type RotateKey struct {
+	Epoch        uint64
+	Version      byte
+	Author       crypto.Token
+	NewToken     crypto.Token
+	Signature    crypto.Signature
+	NewSignature crypto.Signature
+}
+
+func (r *RotateKey) Tokens() []crypto.Token {
+	return []crypto.Token{r.Author, r.NewToken}
+}
+
+func (r *RotateKey) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(r.Author)
+	newHash := crypto.HashToken(r.NewToken)
+	if !v.HasMember(r.Author) {
+		return reject(RotateKeyType, NotMember, memberHash)
+	}
+	if !thresholdApproved(v, r.Author) {
+		return reject(RotateKeyType, ThresholdNotMet, memberHash)
+	}
+	if v.IsRetired(r.NewToken) {
+		return reject(RotateKeyType, RetiredToken, newHash)
+	}
+	if v.HasMember(r.NewToken) {
+		return reject(RotateKeyType, AlreadyMember, newHash)
+	}
+	if !v.SetRotation(r.Author, r.NewToken) {
+		return reject(RotateKeyType, PendingRotation, memberHash)
+	}
+	return accept(RotateKeyType, memberHash, newHash)
+}
+
+func (r *RotateKey) Kind() byte {
+	return RotateKeyType
+}
+
+func (r *RotateKey) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, r.Epoch, RotateKeyType, r.Version)
+	util.PutToken(r.Author, &bytes)
+	util.PutToken(r.NewToken, &bytes)
+	return bytes
+}
+
+func (r *RotateKey) Serialize() []byte {
+	return r.serialize(AxeProtocolCode)
+}
+
+func (r *RotateKey) serialize(protocol [4]byte) []byte {
+	bytes := r.serializeToSign(protocol)
+	util.PutSignature(r.Signature, &bytes)
+	util.PutSignature(r.NewSignature, &bytes)
+	return bytes
+}
+
+// Sign signs the rotation with the key of Author.
+func (r *RotateKey) Sign(pk crypto.PrivateKey) {
+	r.sign(AxeProtocolCode, pk)
+}
+
+func (r *RotateKey) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := r.serializeToSign(protocol)
+	r.Signature = pk.Sign(bytes)
+}
+
+// CounterSign signs the rotation with the key of NewToken.
+func (r *RotateKey) CounterSign(pk crypto.PrivateKey) {
+	r.counterSign(AxeProtocolCode, pk)
+}
+
+func (r *RotateKey) counterSign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := r.serializeToSign(protocol)
+	r.NewSignature = pk.Sign(bytes)
+}
+
+func ParseRotateKey(data []byte) *RotateKey {
+	rotate, err := DecodeRotateKey(data)
+	if err != nil {
+		return nil
+	}
+	return rotate
+}
+
+// DecodeRotateKey is like ParseRotateKey but returns the reason the action
+// could not be parsed.
+func DecodeRotateKey(data []byte) (*RotateKey, error) {
+	return decodeRotateKey(data, AxeProtocolCode)
+}
+
+func decodeRotateKey(data []byte, protocol [4]byte) (*RotateKey, error) {
+	var err error
+	rotate := RotateKey{}
+	position := 0
+	if rotate.Epoch, rotate.Version, position, err = parseHeader(data, protocol, RotateKeyType); err != nil {
+		return nil, err
+	}
+	rotate.Author, position = util.ParseToken(data, position)
+	rotate.NewToken, position = util.ParseToken(data, position)
+	hashPosition := position
+	if rotate.Signature, err = parseTail(data, position, rotate.Author); err != nil {
+		return nil, err
+	}
+	position += crypto.SignatureSize
+	rotate.NewSignature, position = util.ParseSignature(data, position)
+	if position > len(data) {
+		return nil, ErrTruncated
+	}
+	if !rotate.NewToken.Verify(data[0:hashPosition], rotate.NewSignature) {
+		return nil, ErrBadSignature
+	}
+	return &rotate, nil
+}
+
+// LeaveNetwork removes the Author from the members. Its profile and granted
+// powers of attorney are dropped and its handle released or kept reserved
+// according to the handle policy of the network.
+type LeaveNetwork struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Signature crypto.Signature
+}
+
+func (l *LeaveNetwork) Tokens() []crypto.Token {
+	return []crypto.Token{l.Author}
+}
+
+func (l *LeaveNetwork) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(l.Author)
+	if !v.HasMember(l.Author) {
+		return reject(LeaveNetworkType, NotMember, memberHash)
+	}
+	if !thresholdApproved(v, l.Author) {
+		return reject(LeaveNetworkType, ThresholdNotMet, memberHash)
+	}
+	v.SetLeave(l.Author)
+	return accept(LeaveNetworkType, memberHash)
+}
+
+func (l *LeaveNetwork) Kind() byte {
+	return LeaveNetworkType
+}
+
+func (l *LeaveNetwork) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, l.Epoch, LeaveNetworkType, l.Version)
+	util.PutToken(l.Author, &bytes)
+	return bytes
+}
+
+func (l *LeaveNetwork) Serialize() []byte {
+	return l.serialize(AxeProtocolCode)
+}
+
+func (l *LeaveNetwork) serialize(protocol [4]byte) []byte {
+	bytes := l.serializeToSign(protocol)
+	util.PutSignature(l.Signature, &bytes)
+	return bytes
+}
+
+func (l *LeaveNetwork) Sign(pk crypto.PrivateKey) {
+	l.sign(AxeProtocolCode, pk)
+}
+
+func (l *LeaveNetwork) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := l.serializeToSign(protocol)
+	l.Signature = pk.Sign(bytes)
+}
+
+func ParseLeaveNetwork(data []byte) *LeaveNetwork {
+	leave, err := DecodeLeaveNetwork(data)
+	if err != nil {
+		return nil
+	}
+	return leave
+}
+
+// DecodeLeaveNetwork is like ParseLeaveNetwork but returns the reason the
+// action could not be parsed.
+func DecodeLeaveNetwork(data []byte) (*LeaveNetwork, error) {
+	return decodeLeaveNetwork(data, AxeProtocolCode)
+}
+
+func decodeLeaveNetwork(data []byte, protocol [4]byte) (*LeaveNetwork, error) {
+	var err error
+	leave := LeaveNetwork{}
+	position := 0
+	if leave.Epoch, leave.Version, position, err = parseHeader(data, protocol, LeaveNetworkType); err != nil {
+		return nil, err
+	}
+	leave.Author, position = util.ParseToken(data, position)
+	if leave.Signature, err = parseTail(data, position, leave.Author); err != nil {
+		return nil, err
+	}
+	return &leave, nil
+}
+
+// IsAxeNonVoid checks if a byte array has the header of an axé action different from
 // the void action. It does not try to parse the instruction, so there is no guarantee
 // that the byte array is a valid axé action.
 func IsAxeNonVoid(action []byte) bool {
-	if len(action) < 15 {
-		return false
-	}
-	if action[0] != 0 || action[1] != 0 || action[10] != 1 || action[11] != 0 || action[12] != 0 || action[13] != 0 || action[14] == 0 {
-		return false
-	}
-	return true
+	return DefaultCodec.IsAxeNonVoid(action)
 }
@@ -0,0 +1,277 @@
+package attorney
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+func newKey() (crypto.Token, crypto.PrivateKey) {
+	return crypto.RandomAsymetricKey()
+}
+
+// signedActions returns a signed action of every axé kind in the given
+// format version.
+func signedActions(version byte) []Action {
+	author, authorKey := newKey()
+	other, otherKey := newKey()
+	third, thirdKey := newKey()
+
+	join := &JoinNetwork{Epoch: 1, Version: version, Author: author, Handle: "ana", Details: `{"name":"ana"}`}
+	join.Sign(authorKey)
+
+	update := &UpdateInfo{Epoch: 2, Version: version, Author: author, Details: `{"city":"salvador"}`, Signer: other}
+	update.Sign(otherKey)
+
+	grant := &GrantPowerOfAttorney{Epoch: 3, Version: version, Author: author, Attorney: other, Fingerprint: []byte{1, 2, 3}}
+	if version >= Version1 {
+		grant.Expiry = 100
+		grant.Scope = &AttorneyScope{Protocols: []uint32{7, 9}, Profile: true}
+		grant.Depth = 2
+	}
+	grant.Sign(authorKey)
+
+	revoke := &RevokePowerOfAttorney{Epoch: 4, Version: version, Author: author, Attorney: other}
+	revoke.Sign(authorKey)
+
+	exchange := &KeyExchange{Epoch: 5, Version: version, Author: author, To: other, Ephemeral: third, Secret: []byte("secret"), Attorney: other}
+	exchange.Sign(otherKey)
+
+	change := &ChangeHandle{Epoch: 6, Version: version, Author: author, Handle: "bia", Signer: author}
+	change.Sign(authorKey)
+
+	rotate := &RotateKey{Epoch: 7, Version: version, Author: author, NewToken: other}
+	rotate.Sign(authorKey)
+	rotate.CounterSign(otherKey)
+
+	leave := &LeaveNetwork{Epoch: 8, Version: version, Author: author}
+	leave.Sign(authorKey)
+
+	delegate := &DelegatePowerOfAttorney{Epoch: 9, Version: version, Author: author, Attorney: other, Delegate: third, Expiry: 50, Depth: 1}
+	delegate.Sign(otherKey)
+
+	threshold := &SetThreshold{Epoch: 10, Version: version, Author: author, Threshold: 2, Signers: []crypto.Token{other, third}}
+	threshold.Sign(authorKey)
+
+	guardians := &SetGuardians{Epoch: 11, Version: version, Author: author, Threshold: 1, Guardians: []crypto.Token{other, third}}
+	guardians.Sign(authorKey)
+
+	recovery := &RequestRecovery{Epoch: 12, Version: version, Author: author, NewToken: third}
+	recovery.Sign(otherKey)
+	recovery.Sign(thirdKey)
+
+	cancel := &CancelRecovery{Epoch: 13, Version: version, Author: author}
+	cancel.Sign(authorKey)
+
+	register := &RegisterProtocol{Epoch: 14, Version: version, Author: author, Protocol: 42, Name: "synergy", Details: `{"url":"synergy"}`}
+	register.Sign(authorKey)
+
+	invite := &Invite{Epoch: 15, Version: version, Author: author, Invitee: other, Expiry: 200}
+	invite.Sign(authorKey)
+
+	joinInvite := &JoinWithInvite{Epoch: 16, Version: version, Author: other, Handle: "caio", Details: `{}`, Inviter: author}
+	joinInvite.Sign(otherKey)
+
+	bundled := &LeaveNetwork{Epoch: 17, Version: version, Author: author}
+	bundled.Sign(authorKey)
+	bundle := &Bundle{Epoch: 17, Version: version, Author: author, Actions: []Action{change, bundled}}
+	bundle.Sign(authorKey)
+
+	cosigned := &Cosigned{Epoch: 18, Version: version, Author: author, Action: leave}
+	cosigned.Sign(otherKey)
+	cosigned.Sign(thirdKey)
+
+	multi := &MultiVoid{Epoch: 19, Version: version, Protocol: 42, Author: author, Data: []byte("payload")}
+	multi.Sign(otherKey)
+	multi.Sign(thirdKey)
+
+	return []Action{join, update, grant, revoke, exchange, change, rotate, leave, delegate, threshold,
+		multi, guardians, recovery, cancel, register, invite, joinInvite, bundle, cosigned}
+}
+
+func TestActionRoundTrip(t *testing.T) {
+	for _, version := range []byte{Version0, Version1} {
+		for _, action := range signedActions(version) {
+			data := action.Serialize()
+			if data == nil {
+				t.Fatalf("kind %d version %d: could not serialize", action.Kind(), version)
+			}
+			parsed, err := ParseAction(data)
+			if err != nil {
+				t.Fatalf("kind %d version %d: could not parse: %v", action.Kind(), version, err)
+			}
+			if parsed.Kind() != action.Kind() {
+				t.Errorf("kind %d version %d: parsed as kind %d", action.Kind(), version, parsed.Kind())
+			}
+			if !bytes.Equal(parsed.Serialize(), data) {
+				t.Errorf("kind %d version %d: round trip changed the encoding", action.Kind(), version)
+			}
+			if got := DefaultCodec.Kind(data); got != action.Kind() {
+				t.Errorf("kind %d version %d: codec reads kind %d", action.Kind(), version, got)
+			}
+		}
+	}
+}
+
+func TestActionVersionHeader(t *testing.T) {
+	leave := &LeaveNetwork{Epoch: 1, Version: Version0}
+	if kind := leave.Serialize()[headerSize-1]; kind != LeaveNetworkType {
+		t.Errorf("version 0 kind byte %x", kind)
+	}
+	leave.Version = Version1
+	data := leave.Serialize()
+	if kind := data[headerSize-1]; kind != LeaveNetworkType|versionFlag {
+		t.Errorf("version 1 kind byte %x", kind)
+	}
+	if data[headerSize] != Version1 {
+		t.Errorf("version byte %d", data[headerSize])
+	}
+	data[headerSize] = CurrentVersion + 1
+	if _, err := ParseAction(data); err == nil {
+		t.Error("parsed an action of an unknown version")
+	}
+}
+
+func TestGrantVersion0Downgrade(t *testing.T) {
+	author, authorKey := newKey()
+	attorney, _ := newKey()
+	restrictions := []func(*GrantPowerOfAttorney){
+		func(g *GrantPowerOfAttorney) { g.Expiry = 10 },
+		func(g *GrantPowerOfAttorney) { g.Scope = &AttorneyScope{Profile: true} },
+		func(g *GrantPowerOfAttorney) { g.Depth = 1 },
+	}
+	for n, restrict := range restrictions {
+		grant := &GrantPowerOfAttorney{Epoch: 1, Version: Version0, Author: author, Attorney: attorney}
+		restrict(grant)
+		grant.Sign(authorKey)
+		if grant.Signature != (crypto.Signature{}) {
+			t.Errorf("restriction %d: signed a version 0 grant", n)
+		}
+		if data := grant.Serialize(); data != nil {
+			t.Errorf("restriction %d: serialized a version 0 grant", n)
+		}
+		grant.Version = Version1
+		grant.Sign(authorKey)
+		parsed := ParseGrantPowerOfAttorney(grant.Serialize())
+		if parsed == nil {
+			t.Fatalf("restriction %d: could not parse version 1 grant", n)
+		}
+		if parsed.Expiry != grant.Expiry || parsed.Depth != grant.Depth || (parsed.Scope == nil) != (grant.Scope == nil) {
+			t.Errorf("restriction %d: version 1 grant lost its restriction", n)
+		}
+	}
+}
+
+func TestVoidEnvelopeRoundTrip(t *testing.T) {
+	author, authorKey := newKey()
+	other, otherKey := newKey()
+	_, walletKey := newKey()
+
+	void := &Void{Epoch: 1, Protocol: 42, Author: author, Data: []byte("payload"), Signer: author}
+	void.Sign(authorKey)
+	multi := &MultiVoid{Epoch: 2, Protocol: 42, Author: author, Data: []byte("payload")}
+	multi.Sign(authorKey)
+	multi.Sign(otherKey)
+	leave := &LeaveNetwork{Epoch: 3, Version: Version1, Author: other}
+	leave.Sign(otherKey)
+
+	for _, action := range []Action{void, multi, leave} {
+		envelope := &Envelope{Action: action, Fee: 5}
+		envelope.Sign(walletKey)
+		data := envelope.Serialize()
+		parsed, err := DecodeEnvelope(data)
+		if err != nil {
+			t.Fatalf("kind %d: could not parse envelope: %v", action.Kind(), err)
+		}
+		if parsed.Action.Kind() != action.Kind() || parsed.Wallet != envelope.Wallet || parsed.Fee != 5 {
+			t.Errorf("kind %d: envelope changed", action.Kind())
+		}
+		if !bytes.Equal(parsed.Serialize(), data) {
+			t.Errorf("kind %d: round trip changed the encoding", action.Kind())
+		}
+	}
+}
+
+func TestBundleRejectsUnencodable(t *testing.T) {
+	author, authorKey := newKey()
+	void := &Void{Epoch: 1, Protocol: 42, Author: author, Signer: author}
+	void.Sign(authorKey)
+	bundle := &Bundle{Epoch: 1, Version: Version1, Author: author, Actions: []Action{void}}
+	if bundle.Serialize() != nil {
+		t.Error("serialized a bundle with a void")
+	}
+	leave := &LeaveNetwork{Epoch: 1, Version: Version1, Author: author}
+	leave.Sign(authorKey)
+	bundle.Actions = make([]Action, MaxBundleSize+1)
+	for n := range bundle.Actions {
+		bundle.Actions[n] = leave
+	}
+	if bundle.Serialize() != nil {
+		t.Error("serialized a bundle over the maximum size")
+	}
+	bundle.Actions = bundle.Actions[:MaxBundleSize]
+	if ParseBundle(bundle.Serialize()) != nil {
+		t.Error("parsed an unsigned bundle")
+	}
+	bundle.Sign(authorKey)
+	if parsed := ParseBundle(bundle.Serialize()); parsed == nil || len(parsed.Actions) != MaxBundleSize {
+		t.Error("could not parse a bundle of maximum size")
+	}
+}
+
+func TestParseNestingDepth(t *testing.T) {
+	author, authorKey := newKey()
+	_, signerKey := newKey()
+	cosign := func(action Action) *Cosigned {
+		cosigned := &Cosigned{Epoch: 1, Version: Version1, Author: author, Action: action}
+		cosigned.Sign(signerKey)
+		return cosigned
+	}
+	bundle := func(action Action) *Bundle {
+		bundle := &Bundle{Epoch: 1, Version: Version1, Author: author, Actions: []Action{action}}
+		bundle.Sign(authorKey)
+		return bundle
+	}
+	leave := &LeaveNetwork{Epoch: 1, Version: Version1, Author: author}
+	leave.Sign(authorKey)
+
+	for _, action := range []Action{cosign(bundle(leave)), bundle(cosign(leave))} {
+		if _, err := ParseAction(action.Serialize()); err != nil {
+			t.Errorf("kind %d: could not parse nested instruction: %v", action.Kind(), err)
+		}
+	}
+	for _, action := range []Action{cosign(bundle(cosign(leave))), bundle(cosign(bundle(leave)))} {
+		if _, err := ParseAction(action.Serialize()); !errors.Is(err, ErrTooDeep) {
+			t.Errorf("kind %d: parsed instruction nested too deep: %v", action.Kind(), err)
+		}
+	}
+}
+
+func TestParseChecksProtocolBeforeKind(t *testing.T) {
+	author, authorKey := newKey()
+	other := NewCodec([4]byte{2, 0, 0, 0})
+	multi := &MultiVoid{Epoch: 1, Version: Version1, Protocol: 42, Author: author, Data: []byte("payload")}
+	other.Sign(multi, authorKey)
+	if _, err := other.Parse(other.Serialize(multi)); err != nil {
+		t.Fatalf("could not parse a multi void of another axé network: %v", err)
+	}
+	if _, err := ParseAction(other.Serialize(multi)); !errors.Is(err, ErrWrongProtocol) {
+		t.Errorf("parsed a multi void of another axé network: %v", err)
+	}
+
+	// a transaction of another protocol whose first byte is the multi void
+	// kind is not an axé action
+	foreign := append(putHeader(protocolCode(42), 1, MultiVoidType, Version0), make([]byte, 200)...)
+	if _, err := ParseAction(foreign); !errors.Is(err, ErrWrongProtocol) {
+		t.Errorf("parsed a transaction of another protocol: %v", err)
+	}
+	void := &Void{Epoch: 1, Protocol: 42, Author: author, Data: []byte("payload"), Signer: author}
+	void.Sign(authorKey)
+	envelope := &Envelope{Action: void}
+	envelope.Sign(authorKey)
+	if _, err := ParseAction(envelope.Serialize()); err != nil {
+		t.Errorf("could not parse a void of another protocol: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package attorney
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// appendLog is an append only file of length prefixed records used to persist
+// the variable sized parts of the state. A nil file keeps nothing on disk.
+type appendLog struct {
+	file *os.File
+}
+
+// newAppendLog creates an empty log at dataPath, discarding any previous one.
+// With an empty dataPath records are not persisted.
+func newAppendLog(name string, dataPath string) *appendLog {
+	if dataPath == "" {
+		return &appendLog{}
+	}
+	path := filepath.Join(dataPath, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		slog.Error("newAppendLog: could not create log", "path", path, "error", err)
+		return nil
+	}
+	return &appendLog{file: file}
+}
+
+// openAppendLog reopens an existing log at dataPath and calls apply for every
+// record in the order they were appended. A truncated last record is ignored
+// and cut from the file, so that records appended later can be replayed.
+func openAppendLog(name string, dataPath string, apply func(record []byte)) *appendLog {
+	path := filepath.Join(dataPath, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("openAppendLog: could not read log", "path", path, "error", err)
+		return nil
+	}
+	position := 0
+	for position+4 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[position:]))
+		if position+4+size > len(data) {
+			break
+		}
+		apply(data[position+4 : position+4+size])
+		position += 4 + size
+	}
+	if position != len(data) {
+		slog.Warn("openAppendLog: truncated log", "path", path)
+		if err := os.Truncate(path, int64(position)); err != nil {
+			slog.Error("openAppendLog: could not truncate log", "path", path, "error", err)
+			return nil
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		slog.Error("openAppendLog: could not open log", "path", path, "error", err)
+		return nil
+	}
+	return &appendLog{file: file}
+}
+
+func (l *appendLog) Append(record []byte) bool {
+	if l.file == nil {
+		return true
+	}
+	data := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(record)), uint32(len(record)))
+	data = append(data, record...)
+	if _, err := l.file.Write(data); err != nil {
+		slog.Error("appendLog.Append: could not write record", "error", err)
+		return false
+	}
+	return true
+}
+
+func (l *appendLog) Close() bool {
+	if l.file == nil {
+		return true
+	}
+	if err := l.file.Close(); err != nil {
+		slog.Error("appendLog.Close", "error", err)
+		return false
+	}
+	l.file = nil
+	return true
+}
@@ -0,0 +1,50 @@
+package attorney
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func replayLog(t *testing.T, dataPath string) ([][]byte, *appendLog) {
+	t.Helper()
+	records := make([][]byte, 0)
+	log := openAppendLog("log", dataPath, func(record []byte) {
+		records = append(records, append([]byte{}, record...))
+	})
+	if log == nil {
+		t.Fatal("could not open log")
+	}
+	return records, log
+}
+
+func TestAppendLogDropsTruncatedRecord(t *testing.T) {
+	dataPath := t.TempDir()
+	log := newAppendLog("log", dataPath)
+	log.Append([]byte("first"))
+	log.Append([]byte("second"))
+	log.Close()
+
+	path := filepath.Join(dataPath, "log")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an interrupted write leaves part of the last record
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+	records, log := replayLog(t, dataPath)
+	if len(records) != 1 || !bytes.Equal(records[0], []byte("first")) {
+		t.Fatalf("replayed %q", records)
+	}
+	log.Append([]byte("third"))
+	log.Close()
+
+	records, log = replayLog(t, dataPath)
+	defer log.Close()
+	if len(records) != 2 || !bytes.Equal(records[1], []byte("third")) {
+		t.Errorf("record appended after a truncated one replayed as %q", records)
+	}
+}
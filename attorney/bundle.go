@@ -0,0 +1,153 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// MaxBundleSize is the maximum number of instructions in a bundle.
+const MaxBundleSize = 255
+
+// Bundle carries up to MaxBundleSize axé instructions of Author under one
+// outer signature of Author. Every instruction is a complete axé action of
+// Author, signed by its own signers, and the bundle is accepted only if all
+// of them are accepted in order. Voids cannot be bundled and bundles cannot
+// be nested.
+type Bundle struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Actions   []Action
+	Signature crypto.Signature
+}
+
+func (b *Bundle) Tokens() []crypto.Token {
+	tokens := []crypto.Token{b.Author}
+	for _, action := range b.Actions {
+		tokens = append(tokens, action.Tokens()...)
+	}
+	return tokens
+}
+
+// Validate validates the instructions of the bundle in order. Their
+// mutations are only kept if every instruction is accepted, otherwise the
+// bundle is rejected with the reason of the first rejected instruction.
+func (b *Bundle) Validate(v ActionValidator) Result {
+	for _, action := range b.Actions {
+		if !action.Tokens()[0].Equal(b.Author) {
+			return reject(BundleType, ForeignInstruction, crypto.HashToken(b.Author))
+		}
+	}
+	return v.Atomically(func(fork ActionValidator) Result {
+		hashes := make([]crypto.Hash, 0)
+		for _, action := range b.Actions {
+			result := action.Validate(fork)
+			if !result.Accepted {
+				return reject(BundleType, result.Reason, result.Hashes...)
+			}
+			hashes = append(hashes, result.Hashes...)
+		}
+		return accept(BundleType, hashes...)
+	})
+}
+
+func (b *Bundle) Kind() byte {
+	return BundleType
+}
+
+// serializeToSign returns nil if the bundle cannot be encoded.
+func (b *Bundle) serializeToSign(protocol [4]byte) []byte {
+	if len(b.Actions) > MaxBundleSize {
+		return nil
+	}
+	for _, action := range b.Actions {
+		if kind := action.Kind(); !isInstruction(kind) || kind == BundleType {
+			return nil
+		}
+	}
+	bytes := putHeader(protocol, b.Epoch, BundleType, b.Version)
+	util.PutToken(b.Author, &bytes)
+	util.PutByte(byte(len(b.Actions)), &bytes)
+	for _, action := range b.Actions {
+		util.PutByteArray(action.serialize(protocol), &bytes)
+	}
+	return bytes
+}
+
+// Serialize returns nil if the bundle has more than MaxBundleSize
+// instructions, a void or a nested bundle.
+func (b *Bundle) Serialize() []byte {
+	return b.serialize(AxeProtocolCode)
+}
+
+func (b *Bundle) serialize(protocol [4]byte) []byte {
+	bytes := b.serializeToSign(protocol)
+	if bytes == nil {
+		return nil
+	}
+	util.PutSignature(b.Signature, &bytes)
+	return bytes
+}
+
+// Sign signs the bundle with the key of Author. Instructions must be signed
+// before they are bundled.
+func (b *Bundle) Sign(pk crypto.PrivateKey) {
+	b.sign(AxeProtocolCode, pk)
+}
+
+func (b *Bundle) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := b.serializeToSign(protocol)
+	if bytes == nil {
+		return
+	}
+	b.Signature = pk.Sign(bytes)
+}
+
+func ParseBundle(data []byte) *Bundle {
+	bundle, err := DecodeBundle(data)
+	if err != nil {
+		return nil
+	}
+	return bundle
+}
+
+// DecodeBundle is like ParseBundle but returns the reason the bundle or any of
+// its instructions could not be parsed.
+func DecodeBundle(data []byte) (*Bundle, error) {
+	return decodeBundle(data, AxeProtocolCode, DefaultCodec.nested(1))
+}
+
+// decodeBundle parses the instructions of the bundle with parse.
+func decodeBundle(data []byte, protocol [4]byte, parse func([]byte) (Action, error)) (*Bundle, error) {
+	var err error
+	bundle := Bundle{}
+	position := 0
+	if bundle.Epoch, bundle.Version, position, err = parseHeader(data, protocol, BundleType); err != nil {
+		return nil, err
+	}
+	bundle.Author, position = util.ParseToken(data, position)
+	if position >= len(data) {
+		return nil, ErrTruncated
+	}
+	count := int(data[position])
+	position = position + 1
+	bundle.Actions = make([]Action, count)
+	codec := NewCodec(protocol)
+	for n := 0; n < count; n++ {
+		var instruction []byte
+		instruction, position = util.ParseByteArray(data, position)
+		if position > len(data) {
+			return nil, ErrTruncated
+		}
+		if kind := codec.Kind(instruction); !isInstruction(kind) || kind == BundleType {
+			return nil, ErrWrongKind
+		}
+		if bundle.Actions[n], err = parse(instruction); err != nil {
+			return nil, err
+		}
+	}
+	if bundle.Signature, err = parseTail(data, position, bundle.Author); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,145 @@
+package attorney
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// accumulatorLanes is the number of 16 bit lanes of an accumulator.
+const accumulatorLanes = 1024
+
+// accumulatorSize is the size in bytes of a serialized accumulator.
+const accumulatorSize = 2 * accumulatorLanes
+
+// accumulator is an order independent commitment to a multiset of elements.
+// It is a lattice based homomorphic multiset hash (LtHash): every element is
+// expanded into 1024 lanes of 16 bits and added lane by lane modulo 2^16, so
+// elements can be added and removed incrementally and two multisets with the
+// same elements always have the same accumulator regardless of insertion
+// order or storage layout. Unlike a sum of 256 bit hashes, finding distinct
+// multisets with the same accumulator is as hard as the short integer
+// solution problem on the lanes.
+type accumulator [accumulatorLanes]uint16
+
+// expand derives the lanes of element from SHA-256 in counter mode.
+func expand(element []byte) *accumulator {
+	var lanes accumulator
+	data := make([]byte, 2+len(element))
+	copy(data[2:], element)
+	for block := 0; block < accumulatorLanes/(crypto.Size/2); block++ {
+		binary.LittleEndian.PutUint16(data, uint16(block))
+		hash := crypto.Hasher(data)
+		for n := 0; n < crypto.Size/2; n++ {
+			lanes[block*crypto.Size/2+n] = binary.LittleEndian.Uint16(hash[2*n:])
+		}
+	}
+	return &lanes
+}
+
+// Add includes element in the accumulated multiset.
+func (a *accumulator) Add(element []byte) {
+	lanes := expand(element)
+	for n := range a {
+		a[n] += lanes[n]
+	}
+}
+
+// Remove excludes element from the accumulated multiset. It must only be
+// called for elements previously added.
+func (a *accumulator) Remove(element []byte) {
+	lanes := expand(element)
+	for n := range a {
+		a[n] -= lanes[n]
+	}
+}
+
+// Digest returns the hash of the accumulator.
+func (a *accumulator) Digest() crypto.Hash {
+	return crypto.Hasher(a.Serialize())
+}
+
+func (a *accumulator) Serialize() []byte {
+	bytes := make([]byte, accumulatorSize)
+	for n, lane := range a {
+		binary.LittleEndian.PutUint16(bytes[2*n:], lane)
+	}
+	return bytes
+}
+
+var ErrInconsistentState = errors.New("axe state files are inconsistent")
+
+// checkpoint records the last incorporated epoch and the config of the state
+// together with the checksum of every store, which Recover compares with the
+// checksums rebuilt when the vault journals and store logs are replayed.
+type checkpoint struct {
+	Epoch         uint64
+	Config        Config
+	Members       crypto.Hash
+	Captions      crypto.Hash
+	Attorneys     crypto.Hash
+	Retired       crypto.Hash
+	Handles       crypto.Hash
+	Tokens        crypto.Hash
+	Profiles      crypto.Hash
+	Grants        crypto.Hash
+	Thresholds    crypto.Hash
+	Guardians     crypto.Hash
+	Recoveries    crypto.Hash
+	Cancellations crypto.Hash
+	Protocols     crypto.Hash
+	Invitations   crypto.Hash
+	Checksum      crypto.Hash
+}
+
+func (c *checkpoint) digests() []*crypto.Hash {
+	return []*crypto.Hash{&c.Members, &c.Captions, &c.Attorneys, &c.Retired, &c.Handles, &c.Tokens, &c.Profiles, &c.Grants, &c.Thresholds, &c.Guardians, &c.Recoveries, &c.Cancellations, &c.Protocols, &c.Invitations, &c.Checksum}
+}
+
+func (c *checkpoint) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	bytes = append(bytes, c.Config.Serialize()...)
+	for _, hash := range c.digests() {
+		util.PutHash(*hash, &bytes)
+	}
+	return bytes
+}
+
+func parseCheckpoint(data []byte) *checkpoint {
+	c := checkpoint{}
+	position := 0
+	c.Epoch, position = util.ParseUint64(data, position)
+	c.Config, position = parseConfig(data, position)
+	for _, hash := range c.digests() {
+		*hash, position = util.ParseHash(data, position)
+	}
+	if position != len(data) {
+		return nil
+	}
+	return &c
+}
+
+// writeCheckpoint atomically replaces the checkpoint file at path.
+func writeCheckpoint(path string, c *checkpoint) error {
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, c.Serialize(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+func readCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := parseCheckpoint(data)
+	if c == nil {
+		return nil, ErrInconsistentState
+	}
+	return c, nil
+}
@@ -0,0 +1,48 @@
+package attorney
+
+import (
+	"testing"
+)
+
+func TestAccumulatorIndependentOfOrder(t *testing.T) {
+	elements := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	var forward, backward, empty accumulator
+	for n := range elements {
+		forward.Add(elements[n])
+		backward.Add(elements[len(elements)-1-n])
+	}
+	if forward.Digest() != backward.Digest() {
+		t.Error("accumulator depends on the order of insertion")
+	}
+	forward.Remove(elements[1])
+	var partial accumulator
+	partial.Add(elements[2])
+	partial.Add(elements[0])
+	if forward.Digest() != partial.Digest() {
+		t.Error("removing an element did not restore the accumulator")
+	}
+	forward.Remove(elements[0])
+	forward.Remove(elements[2])
+	if forward.Digest() != empty.Digest() {
+		t.Error("removing every element did not empty the accumulator")
+	}
+	digest := partial.Digest()
+	partial.Add(elements[0])
+	if partial.Digest() == digest {
+		t.Error("accumulator ignores the multiplicity of elements")
+	}
+}
+
+func TestChecksumIndependentOfActionOrder(t *testing.T) {
+	first, second := newMember(), newMember()
+	states := []*State{newTestState(t, DefaultConfig()), newTestState(t, DefaultConfig())}
+	mustAccept(t, states[0], 1, first.key, signedJoin(1, first, "first"), signedJoin(1, second, "second"))
+	mustAccept(t, states[1], 1, first.key, signedJoin(1, second, "second"), signedJoin(1, first, "first"))
+	if states[0].ChecksumPoint() != states[1].ChecksumPoint() {
+		t.Error("joins validated in a different order changed the checksum")
+	}
+	mustAccept(t, states[0], 2, second.key, signedLeave(2, second))
+	if states[0].ChecksumPoint() == states[1].ChecksumPoint() {
+		t.Error("a leave did not change the checksum")
+	}
+}
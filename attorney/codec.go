@@ -0,0 +1,122 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/protocol/actions"
+)
+
+// Codec encodes and parses the actions of one axé network, identified by the
+// protocol code its actions carry on the breeze chain. Several axé networks
+// can coexist on one chain as long as their codes differ. Void actions carry
+// the code of the protocol they belong to and are understood by every codec.
+type Codec struct {
+	protocol [4]byte
+}
+
+// DefaultCodec is the codec of the public axé network, used by ParseAction,
+// GetTokens, Kind and IsAxeNonVoid. The Serialize and Sign methods of actions
+// and the Parse and Decode functions of every kind also use its protocol code.
+var DefaultCodec = NewCodec(AxeProtocolCode)
+
+// NewCodec returns a codec for the axé network with the given protocol code.
+func NewCodec(protocol [4]byte) *Codec {
+	return &Codec{protocol: protocol}
+}
+
+// Protocol returns the protocol code of the codec.
+func (c *Codec) Protocol() [4]byte {
+	return c.protocol
+}
+
+// Serialize serializes action under the protocol code of the codec.
+func (c *Codec) Serialize(action Action) []byte {
+	return action.serialize(c.protocol)
+}
+
+// Sign signs action under the protocol code of the codec.
+func (c *Codec) Sign(action Action, pk crypto.PrivateKey) {
+	action.sign(c.protocol, pk)
+}
+
+// CounterSign countersigns a key rotation under the protocol code of the
+// codec.
+func (c *Codec) CounterSign(rotate *RotateKey, pk crypto.PrivateKey) {
+	rotate.counterSign(c.protocol, pk)
+}
+
+// MaxNestingDepth is how deep an instruction may be nested in bundles and
+// cosigned actions, enough for a bundle of cosigned instructions or a
+// cosigned bundle.
+const MaxNestingDepth = 2
+
+// Parse parses any action of the axé network of the codec. Void actions may
+// carry any protocol code, other kinds must carry the code of the codec.
+func (c *Codec) Parse(data []byte) (Action, error) {
+	return c.parse(data, 0)
+}
+
+// nested returns the parser of instructions nested depth levels deep.
+func (c *Codec) nested(depth int) func([]byte) (Action, error) {
+	return func(data []byte) (Action, error) {
+		return c.parse(data, depth)
+	}
+}
+
+// parse parses an action nested depth levels deep in other actions.
+func (c *Codec) parse(data []byte, depth int) (Action, error) {
+	if depth > MaxNestingDepth {
+		return nil, ErrTooDeep
+	}
+	if len(data) < headerSize {
+		return nil, ErrTruncated
+	}
+	// the kind byte of actions of other protocols means nothing to axé,
+	// except for the void kind of the voids they carry
+	if !c.isProtocol(data) && data[14] != VoidType {
+		return nil, ErrWrongProtocol
+	}
+	decode, ok := decoders[data[14]&^versionFlag]
+	if !ok {
+		return nil, ErrWrongKind
+	}
+	return decode(data, c.protocol, c.nested(depth+1))
+}
+
+// anyProtocol adapts the decoder of a kind that may carry any protocol code.
+func anyProtocol[T Action](decode func([]byte) (T, error)) decodeFunc {
+	return decoder(func(data []byte, _ [4]byte) (T, error) { return decode(data) })
+}
+
+// GetTokens returns the tokens involved in an action of the axé network of
+// the codec, or nil if it cannot be parsed.
+func (c *Codec) GetTokens(data []byte) []crypto.Token {
+	action, err := c.Parse(data)
+	if err != nil {
+		return nil
+	}
+	return action.Tokens()
+}
+
+// Kind returns the axé kind of data if it carries the protocol code of the
+// codec, or Invalid otherwise.
+func (c *Codec) Kind(data []byte) byte {
+	if len(data) < headerSize {
+		return Invalid
+	}
+	if data[0] != 0 || data[1] != actions.IVoid || !c.isProtocol(data) {
+		return Invalid
+	}
+	return data[14] &^ versionFlag
+}
+
+// IsAxeNonVoid checks if data has the header of an action of the axé network
+// of the codec other than a void action. It does not try to parse the action,
+// so there is no guarantee that data is a valid axé action.
+func (c *Codec) IsAxeNonVoid(data []byte) bool {
+	kind := c.Kind(data)
+	return kind != Invalid && kind != VoidType
+}
+
+func (c *Codec) isProtocol(data []byte) bool {
+	return data[10] == c.protocol[0] && data[11] == c.protocol[1] && data[12] == c.protocol[2] && data[13] == c.protocol[3]
+}
@@ -0,0 +1,67 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/util"
+)
+
+// Config holds the consensus settings of an axé network. Every node of a
+// network must validate with the same Config, so it is fixed when the genesis
+// state is created and recorded in its checkpoints, and a state is only
+// recovered with the config it was created with.
+type Config struct {
+	// Protocol is the protocol code carried by the actions of the network.
+	Protocol [4]byte
+	// HandlePolicy decides what happens to the handle of a leaving member.
+	HandlePolicy HandlePolicy
+	// RecoveryDelay is the number of epochs a recovery waits before it
+	// reassigns an identity.
+	RecoveryDelay uint64
+	// RequireRegistered rejects voids targeting protocol codes missing from
+	// the registry.
+	RequireRegistered bool
+	// RequireInvitation makes new members join with an invitation of an
+	// existing member.
+	RequireInvitation bool
+	// JoinsPerBlock is the maximum number of members joining in a block and
+	// JoinsPerWallet the maximum joining from transactions paid by the same
+	// wallet in a block. Zero means no limit.
+	JoinsPerBlock  uint32
+	JoinsPerWallet uint32
+}
+
+// DefaultConfig returns the config of the public axé network.
+func DefaultConfig() Config {
+	return Config{
+		Protocol:      AxeProtocolCode,
+		HandlePolicy:  TombstoneHandle,
+		RecoveryDelay: DefaultRecoveryDelay,
+	}
+}
+
+func (c Config) Serialize() []byte {
+	bytes := append(make([]byte, 0), c.Protocol[:]...)
+	util.PutByte(byte(c.HandlePolicy), &bytes)
+	util.PutUint64(c.RecoveryDelay, &bytes)
+	util.PutBool(c.RequireRegistered, &bytes)
+	util.PutBool(c.RequireInvitation, &bytes)
+	util.PutUint32(c.JoinsPerBlock, &bytes)
+	util.PutUint32(c.JoinsPerWallet, &bytes)
+	return bytes
+}
+
+func parseConfig(data []byte, position int) (Config, int) {
+	var c Config
+	if position+len(c.Protocol) > len(data) {
+		return c, len(data) + 1
+	}
+	position += copy(c.Protocol[:], data[position:])
+	var policy byte
+	policy, position = util.ParseByte(data, position)
+	c.HandlePolicy = HandlePolicy(policy)
+	c.RecoveryDelay, position = util.ParseUint64(data, position)
+	c.RequireRegistered, position = util.ParseBool(data, position)
+	c.RequireInvitation, position = util.ParseBool(data, position)
+	c.JoinsPerBlock, position = util.ParseUint32(data, position)
+	c.JoinsPerWallet, position = util.ParseUint32(data, position)
+	return c, position
+}
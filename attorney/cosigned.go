@@ -0,0 +1,156 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// Cosigned carries one axé instruction of Author cosigned by signers of the
+// threshold policy of Author. Once a member sets a threshold policy, the
+// actions that change who controls its identity (SetThreshold, SetGuardians,
+// RotateKey, LeaveNetwork, GrantPowerOfAttorney and CancelRecovery) are only
+// accepted inside a Cosigned action meeting the policy. The instruction is a
+// complete axé action signed by its own signer, which cannot be a void or
+// another cosigned action.
+type Cosigned struct {
+	Epoch      uint64
+	Version    byte
+	Author     crypto.Token
+	Action     Action
+	Signers    []crypto.Token
+	Signatures []crypto.Signature
+}
+
+// thresholdApproved checks that an action changing the control of the
+// identity of author meets the threshold policy of author, if any.
+func thresholdApproved(v ActionValidator, author crypto.Token) bool {
+	if _, ok := v.ThresholdOf(author); !ok {
+		return true
+	}
+	return v.IsCosigned(author)
+}
+
+// isInstruction checks if an action of the given kind can be carried inside
+// another axé action. Voids are parsed up to the breeze envelope tail, so they
+// cannot be nested, and neither can the voids of several signers.
+func isInstruction(kind byte) bool {
+	return kind != Invalid && kind != VoidType && kind != MultiVoidType
+}
+
+func (c *Cosigned) Tokens() []crypto.Token {
+	tokens := []crypto.Token{c.Author}
+	if c.Action != nil {
+		tokens = append(tokens, c.Action.Tokens()...)
+	}
+	return append(tokens, c.Signers...)
+}
+
+// Validate checks the cosigners against the threshold policy of Author and
+// validates the instruction with the policy met. A rejected instruction
+// rejects the cosigned action with its reason.
+func (c *Cosigned) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(c.Author)
+	if c.Epoch > v.Epoch() {
+		return reject(CosignedType, FutureEpoch)
+	}
+	policy, ok := v.ThresholdOf(c.Author)
+	if !ok {
+		return reject(CosignedType, InvalidThreshold, memberHash)
+	}
+	if !policy.Met(v, c.Signers) {
+		return reject(CosignedType, ThresholdNotMet, memberHash)
+	}
+	return v.Cosigned(c.Author, func(cosigned ActionValidator) Result {
+		result := c.Action.Validate(cosigned)
+		if !result.Accepted {
+			return reject(CosignedType, result.Reason, result.Hashes...)
+		}
+		return accept(CosignedType, result.Hashes...)
+	})
+}
+
+func (c *Cosigned) Kind() byte {
+	return CosignedType
+}
+
+// serializeToSign returns nil if the action carries no valid instruction.
+func (c *Cosigned) serializeToSign(protocol [4]byte) []byte {
+	if c.Action == nil || !isInstruction(c.Action.Kind()) || c.Action.Kind() == CosignedType || len(c.Signers) > 255 {
+		return nil
+	}
+	bytes := putHeader(protocol, c.Epoch, CosignedType, c.Version)
+	util.PutToken(c.Author, &bytes)
+	util.PutByteArray(c.Action.serialize(protocol), &bytes)
+	return bytes
+}
+
+// Serialize returns nil if the instruction is missing, is a void or is
+// another cosigned action.
+func (c *Cosigned) Serialize() []byte {
+	return c.serialize(AxeProtocolCode)
+}
+
+func (c *Cosigned) serialize(protocol [4]byte) []byte {
+	bytes := c.serializeToSign(protocol)
+	if bytes == nil {
+		return nil
+	}
+	putCosigners(c.Signers, c.Signatures, &bytes)
+	return bytes
+}
+
+// Sign adds the signature of pk to the action. The instruction must be signed
+// before it is cosigned.
+func (c *Cosigned) Sign(pk crypto.PrivateKey) {
+	c.sign(AxeProtocolCode, pk)
+}
+
+func (c *Cosigned) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := c.serializeToSign(protocol)
+	if bytes == nil {
+		return
+	}
+	c.Signers = append(c.Signers, pk.PublicKey())
+	c.Signatures = append(c.Signatures, pk.Sign(bytes))
+}
+
+func ParseCosigned(data []byte) *Cosigned {
+	cosigned, err := DecodeCosigned(data)
+	if err != nil {
+		return nil
+	}
+	return cosigned
+}
+
+// DecodeCosigned is like ParseCosigned but returns the reason the action or
+// its instruction could not be parsed. Every signature must be valid.
+func DecodeCosigned(data []byte) (*Cosigned, error) {
+	return decodeCosigned(data, AxeProtocolCode, DefaultCodec.nested(1))
+}
+
+// decodeCosigned parses the instruction of the action with parse.
+func decodeCosigned(data []byte, protocol [4]byte, parse func([]byte) (Action, error)) (*Cosigned, error) {
+	var err error
+	cosigned := Cosigned{}
+	position := 0
+	if cosigned.Epoch, cosigned.Version, position, err = parseHeader(data, protocol, CosignedType); err != nil {
+		return nil, err
+	}
+	cosigned.Author, position = util.ParseToken(data, position)
+	var instruction []byte
+	instruction, position = util.ParseByteArray(data, position)
+	if position > len(data) {
+		return nil, ErrTruncated
+	}
+	codec := NewCodec(protocol)
+	if kind := codec.Kind(instruction); !isInstruction(kind) || kind == CosignedType {
+		return nil, ErrWrongKind
+	}
+	if cosigned.Action, err = parse(instruction); err != nil {
+		return nil, err
+	}
+	if cosigned.Signers, cosigned.Signatures, err = parseCosigners(data, position); err != nil {
+		return nil, err
+	}
+	return &cosigned, nil
+}
@@ -0,0 +1,139 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// MaxDelegationDepth is the maximum depth of onward delegation a member may
+// allow in a power of attorney.
+const MaxDelegationDepth = 4
+
+// DelegatePowerOfAttorney is signed by an Attorney of Author to grant onward
+// power of attorney to Delegate, for instance a signing worker of an
+// attorney service. The attorney grant must allow delegation at a Depth
+// larger than the one delegated, and its Scope must contain the delegated
+// one. Revoking the attorney grant invalidates every grant delegated down
+// from it.
+type DelegatePowerOfAttorney struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Attorney  crypto.Token
+	Delegate  crypto.Token
+	Expiry    uint64
+	Scope     *AttorneyScope
+	Depth     byte
+	Signature crypto.Signature
+}
+
+func (d *DelegatePowerOfAttorney) Tokens() []crypto.Token {
+	return []crypto.Token{d.Author, d.Attorney, d.Delegate}
+}
+
+func (d *DelegatePowerOfAttorney) Validate(v ActionValidator) Result {
+	hash := attorneyHash(d.Author, d.Delegate)
+	if !v.HasMember(d.Author) {
+		return reject(DelegatePowerOfAttorneyType, NotMember, crypto.HashToken(d.Author))
+	}
+	parent, ok := v.GrantOf(d.Author, d.Attorney)
+	if !ok {
+		return reject(DelegatePowerOfAttorneyType, NoPowerOfAttorney, attorneyHash(d.Author, d.Attorney))
+	}
+	if parent.Depth == 0 {
+		return reject(DelegatePowerOfAttorneyType, DelegationNotAllowed, parent.Hash())
+	}
+	if d.Depth >= parent.Depth {
+		return reject(DelegatePowerOfAttorneyType, DelegationTooDeep, parent.Hash())
+	}
+	if parent.Scope != nil && !parent.Scope.Contains(d.Scope) {
+		return reject(DelegatePowerOfAttorneyType, ScopeExceeded, parent.Hash())
+	}
+	if _, ok := v.GrantOf(d.Author, d.Delegate); ok || d.Author.Equal(d.Delegate) {
+		return reject(DelegatePowerOfAttorneyType, AlreadyAttorney, hash)
+	}
+	if d.Expiry != 0 && d.Expiry < v.Epoch() {
+		return reject(DelegatePowerOfAttorneyType, Expired, hash)
+	}
+	grant := AttorneyGrant{
+		Author:   d.Author,
+		Attorney: d.Delegate,
+		Expiry:   d.Expiry,
+		Scope:    d.Scope,
+		Depth:    d.Depth,
+		Via:      d.Attorney,
+	}
+	v.SetNewGrantPower(grant)
+	return accept(DelegatePowerOfAttorneyType, hash)
+}
+
+func (d *DelegatePowerOfAttorney) Kind() byte {
+	return DelegatePowerOfAttorneyType
+}
+
+func (d *DelegatePowerOfAttorney) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, d.Epoch, DelegatePowerOfAttorneyType, d.Version)
+	util.PutToken(d.Author, &bytes)
+	util.PutToken(d.Attorney, &bytes)
+	util.PutToken(d.Delegate, &bytes)
+	util.PutUint64(d.Expiry, &bytes)
+	putScope(d.Scope, &bytes)
+	util.PutByte(d.Depth, &bytes)
+	return bytes
+}
+
+func (d *DelegatePowerOfAttorney) Serialize() []byte {
+	return d.serialize(AxeProtocolCode)
+}
+
+func (d *DelegatePowerOfAttorney) serialize(protocol [4]byte) []byte {
+	bytes := d.serializeToSign(protocol)
+	util.PutSignature(d.Signature, &bytes)
+	return bytes
+}
+
+// Sign signs the delegation with the key of Attorney.
+func (d *DelegatePowerOfAttorney) Sign(pk crypto.PrivateKey) {
+	d.sign(AxeProtocolCode, pk)
+}
+
+func (d *DelegatePowerOfAttorney) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := d.serializeToSign(protocol)
+	d.Signature = pk.Sign(bytes)
+}
+
+func ParseDelegatePowerOfAttorney(data []byte) *DelegatePowerOfAttorney {
+	delegate, err := DecodeDelegatePowerOfAttorney(data)
+	if err != nil {
+		return nil
+	}
+	return delegate
+}
+
+// DecodeDelegatePowerOfAttorney is like ParseDelegatePowerOfAttorney but
+// returns the reason the action could not be parsed.
+func DecodeDelegatePowerOfAttorney(data []byte) (*DelegatePowerOfAttorney, error) {
+	return decodeDelegatePowerOfAttorney(data, AxeProtocolCode)
+}
+
+func decodeDelegatePowerOfAttorney(data []byte, protocol [4]byte) (*DelegatePowerOfAttorney, error) {
+	var err error
+	delegate := DelegatePowerOfAttorney{}
+	position := 0
+	if delegate.Epoch, delegate.Version, position, err = parseHeader(data, protocol, DelegatePowerOfAttorneyType); err != nil {
+		return nil, err
+	}
+	delegate.Author, position = util.ParseToken(data, position)
+	delegate.Attorney, position = util.ParseToken(data, position)
+	delegate.Delegate, position = util.ParseToken(data, position)
+	delegate.Expiry, position = util.ParseUint64(data, position)
+	delegate.Scope, position = parseScope(data, position)
+	if position >= len(data) {
+		return nil, ErrTruncated
+	}
+	delegate.Depth, position = data[position], position+1
+	if delegate.Signature, err = parseTail(data, position, delegate.Attorney); err != nil {
+		return nil, err
+	}
+	return &delegate, nil
+}
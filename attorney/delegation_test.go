@@ -0,0 +1,43 @@
+package attorney
+
+import (
+	"testing"
+)
+
+func signedDelegation(epoch uint64, author, attorney, delegate testMember, depth byte) *DelegatePowerOfAttorney {
+	action := &DelegatePowerOfAttorney{Epoch: epoch, Version: CurrentVersion, Author: author.token, Attorney: attorney.token, Delegate: delegate.token, Depth: depth}
+	action.Sign(attorney.key)
+	return action
+}
+
+func TestDelegationChain(t *testing.T) {
+	author, attorney, delegate, worker := newMember(), newMember(), newMember(), newMember()
+	state := newTestState(t, DefaultConfig())
+	mustAccept(t, state, 1, author.key, signedJoin(1, author, "author"))
+	mustAccept(t, state, 2, author.key, signedGrant(2, author, attorney.token))
+	if result := check(state.Validator(3), attorney.key, signedDelegation(3, author, attorney, delegate, 0)); result.Reason != DelegationNotAllowed {
+		t.Errorf("delegated a grant without depth: %v", result.Reason)
+	}
+
+	grant := &GrantPowerOfAttorney{Epoch: 3, Version: CurrentVersion, Author: author.token, Attorney: worker.token, Depth: 1}
+	grant.Sign(author.key)
+	mustAccept(t, state, 3, author.key, grant)
+	if result := check(state.Validator(4), worker.key, signedDelegation(4, author, worker, delegate, 1)); result.Reason != DelegationTooDeep {
+		t.Errorf("delegated at the depth of the parent grant: %v", result.Reason)
+	}
+	mustAccept(t, state, 4, worker.key, signedDelegation(4, author, worker, delegate, 0))
+	if !state.PowerOfAttorney(author.token, delegate.token) {
+		t.Fatal("delegated grant not in force")
+	}
+
+	// revoking the parent grant invalidates the delegated one
+	revoke := &RevokePowerOfAttorney{Epoch: 5, Version: CurrentVersion, Author: author.token, Attorney: worker.token}
+	revoke.Sign(author.key)
+	mustAccept(t, state, 5, author.key, revoke)
+	if state.PowerOfAttorney(author.token, delegate.token) {
+		t.Error("delegated grant in force after its parent was revoked")
+	}
+	if _, ok := state.Validator(6).GrantOf(author.token, delegate.token); ok {
+		t.Error("delegated grant valid for validation after its parent was revoked")
+	}
+}
@@ -0,0 +1,113 @@
+package attorney
+
+import (
+	"bytes"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// envelopeTailSize is the size of the breeze wallet, fee and signature that
+// follow an axé action in a breeze void transaction.
+const envelopeTailSize = crypto.TokenSize + 8 + crypto.SignatureSize
+
+// Envelope is the breeze void transaction carrying an axé action. The wallet
+// pays the fee and signs the whole transaction, axé signatures included.
+type Envelope struct {
+	Action    Action
+	Wallet    crypto.Token
+	Fee       uint64
+	Signature crypto.Signature
+}
+
+// serializeToSign returns nil if the action cannot be encoded.
+func (e *Envelope) serializeToSign(protocol [4]byte) []byte {
+	bytes := e.Action.serialize(protocol)
+	if bytes == nil {
+		return nil
+	}
+	util.PutToken(e.Wallet, &bytes)
+	util.PutUint64(e.Fee, &bytes)
+	return bytes
+}
+
+func (e *Envelope) serialize(protocol [4]byte) []byte {
+	bytes := e.serializeToSign(protocol)
+	if bytes == nil {
+		return nil
+	}
+	util.PutSignature(e.Signature, &bytes)
+	return bytes
+}
+
+// Serialize returns the complete breeze void transaction, or nil if the
+// action cannot be encoded.
+func (e *Envelope) Serialize() []byte {
+	return e.serialize(AxeProtocolCode)
+}
+
+// Sign sets the wallet of the transaction to the token of wallet and signs
+// it. The action must be signed before.
+func (e *Envelope) Sign(wallet crypto.PrivateKey) {
+	e.sign(AxeProtocolCode, wallet)
+}
+
+func (e *Envelope) sign(protocol [4]byte, wallet crypto.PrivateKey) {
+	e.Wallet = wallet.PublicKey()
+	if bytes := e.serializeToSign(protocol); bytes != nil {
+		e.Signature = wallet.Sign(bytes)
+	}
+}
+
+// SerializeEnvelope returns the complete breeze void transaction of envelope
+// under the protocol code of the codec.
+func (c *Codec) SerializeEnvelope(envelope *Envelope) []byte {
+	return envelope.serialize(c.protocol)
+}
+
+// SignEnvelope signs envelope with the wallet key under the protocol code of
+// the codec.
+func (c *Codec) SignEnvelope(envelope *Envelope, wallet crypto.PrivateKey) {
+	envelope.sign(c.protocol, wallet)
+}
+
+// ParseEnvelope parses a breeze void transaction carrying an action of the
+// axé network of the codec. The action must take every byte up to the
+// breeze tail and the wallet signature must be valid.
+func (c *Codec) ParseEnvelope(data []byte) (*Envelope, error) {
+	if len(data) < headerSize+envelopeTailSize {
+		return nil, ErrTruncated
+	}
+	// axé actions are parsed from the start of the transaction, voids find
+	// their signer from its end
+	action, err := c.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	tail := len(data) - envelopeTailSize
+	if !bytes.Equal(action.serialize(c.protocol), data[:tail]) {
+		return nil, ErrBadEnvelope
+	}
+	envelope := Envelope{Action: action}
+	position := tail
+	envelope.Wallet, position = util.ParseToken(data, position)
+	envelope.Fee, position = util.ParseUint64(data, position)
+	if envelope.Signature, err = parseTail(data, position, envelope.Wallet); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
+
+func ParseEnvelope(data []byte) *Envelope {
+	envelope, err := DecodeEnvelope(data)
+	if err != nil {
+		return nil
+	}
+	return envelope
+}
+
+// DecodeEnvelope is like ParseEnvelope but returns the reason the transaction
+// could not be parsed.
+func DecodeEnvelope(data []byte) (*Envelope, error) {
+	return DefaultCodec.ParseEnvelope(data)
+}
@@ -0,0 +1,76 @@
+package attorney
+
+import (
+	"errors"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/protocol/actions"
+	"github.com/freehandle/breeze/util"
+)
+
+// Errors returned by the Decode* functions. They can be inspected with
+// errors.Is to tell why an action was rejected.
+var (
+	ErrBadVersion    = errors.New("axe: unsupported breeze version")
+	ErrWrongProtocol = errors.New("axe: wrong protocol code")
+	ErrWrongKind     = errors.New("axe: wrong action kind")
+	ErrTruncated     = errors.New("axe: truncated action")
+	ErrInvalidJSON   = errors.New("axe: invalid JSON details")
+	ErrBadSignature  = errors.New("axe: bad signature")
+	ErrFormatVersion = errors.New("axe: unsupported axe format version")
+	ErrBadEnvelope   = errors.New("axe: malformed breeze envelope")
+	ErrTooDeep       = errors.New("axe: actions nested too deep")
+)
+
+// headerSize is the size of the breeze void header followed by the axé kind.
+const headerSize = 15
+
+// parseHeader checks the breeze void header of an axé action of the given
+// kind under the given protocol code and returns its epoch, its axé format version and the position of the
+// first axé field.
+func parseHeader(data []byte, protocol [4]byte, kind byte) (uint64, byte, int, error) {
+	if len(data) < headerSize {
+		return 0, 0, 0, ErrTruncated
+	}
+	if data[0] != 0 {
+		return 0, 0, 0, ErrBadVersion
+	}
+	if data[1] != actions.IVoid {
+		return 0, 0, 0, ErrWrongKind
+	}
+	epoch, position := util.ParseUint64(data, 2)
+	if data[position] != protocol[0] || data[position+1] != protocol[1] || data[position+2] != protocol[2] || data[position+3] != protocol[3] {
+		return 0, 0, 0, ErrWrongProtocol
+	}
+	if data[position+4]&^versionFlag != kind {
+		return 0, 0, 0, ErrWrongKind
+	}
+	if data[position+4]&versionFlag == 0 {
+		return epoch, Version0, position + 5, nil
+	}
+	if len(data) <= headerSize {
+		return 0, 0, 0, ErrTruncated
+	}
+	version := data[position+5]
+	if version == Version0 || version > CurrentVersion {
+		return 0, 0, 0, ErrFormatVersion
+	}
+	return epoch, version, position + 6, nil
+}
+
+// parseTail parses the axé signature starting at position and checks it
+// against the preceding bytes with the signer token.
+func parseTail(data []byte, position int, signer crypto.Token) (crypto.Signature, error) {
+	if position > len(data) {
+		return crypto.Signature{}, ErrTruncated
+	}
+	hashPosition := position
+	signature, position := util.ParseSignature(data, position)
+	if position > len(data) {
+		return signature, ErrTruncated
+	}
+	if !signer.Verify(data[0:hashPosition], signature) {
+		return signature, ErrBadSignature
+	}
+	return signature, nil
+}
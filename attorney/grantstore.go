@@ -0,0 +1,247 @@
+package attorney
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// AttorneyScope restricts what an attorney may sign on behalf of the author:
+// Void actions of the listed protocol codes and, if Profile is set, updates
+// of the author profile and handle.
+type AttorneyScope struct {
+	Protocols []uint32
+	Profile   bool
+}
+
+func (a *AttorneyScope) AllowsProtocol(protocol uint32) bool {
+	for _, code := range a.Protocols {
+		if code == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AttorneyScope) AllowsProfile() bool {
+	return a.Profile
+}
+
+// Contains checks if every action allowed by other is allowed by the scope.
+// A nil other is unrestricted and is never contained.
+func (a *AttorneyScope) Contains(other *AttorneyScope) bool {
+	if other == nil {
+		return false
+	}
+	if other.Profile && !a.Profile {
+		return false
+	}
+	for _, code := range other.Protocols {
+		if !a.AllowsProtocol(code) {
+			return false
+		}
+	}
+	return true
+}
+
+// putScope serializes an optional scope, nil meaning unrestricted.
+func putScope(scope *AttorneyScope, data *[]byte) {
+	if scope == nil {
+		util.PutByte(0, data)
+		return
+	}
+	util.PutByte(1, data)
+	util.PutUint32(uint32(len(scope.Protocols)), data)
+	for _, code := range scope.Protocols {
+		util.PutUint32(code, data)
+	}
+	if scope.Profile {
+		util.PutByte(1, data)
+	} else {
+		util.PutByte(0, data)
+	}
+}
+
+func parseScope(data []byte, position int) (*AttorneyScope, int) {
+	if position >= len(data) {
+		return nil, len(data) + 1
+	}
+	if data[position] == 0 {
+		return nil, position + 1
+	}
+	count, position := util.ParseUint32(data, position+1)
+	if position > len(data) || int(count) > (len(data)-position)/4 {
+		return nil, len(data) + 1
+	}
+	scope := AttorneyScope{Protocols: make([]uint32, count)}
+	for n := 0; n < int(count); n++ {
+		scope.Protocols[n], position = util.ParseUint32(data, position)
+	}
+	if position >= len(data) {
+		return nil, len(data) + 1
+	}
+	scope.Profile = data[position] != 0
+	return &scope, position + 1
+}
+
+// AttorneyGrant is a power of attorney granted by Author to Attorney. A non
+// zero Expiry is the last epoch at which the grant is valid. A nil Scope
+// grants unrestricted power. Depth is how many further levels of delegation
+// the attorney may grant onward. Delegated grants record in Via the attorney
+// that delegated them and are only valid while the grant of Via is.
+type AttorneyGrant struct {
+	Author   crypto.Token
+	Attorney crypto.Token
+	Expiry   uint64
+	Scope    *AttorneyScope
+	Depth    byte
+	Via      crypto.Token
+}
+
+// Delegated checks if the grant was delegated by another attorney.
+func (g AttorneyGrant) Delegated() bool {
+	return g.Via != crypto.Token{}
+}
+
+// ValidAt checks if the grant has not expired at epoch.
+func (g AttorneyGrant) ValidAt(epoch uint64) bool {
+	return g.Expiry == 0 || epoch <= g.Expiry
+}
+
+// Hash is the hash of the grant in the attorneys vault.
+func (g AttorneyGrant) Hash() crypto.Hash {
+	return attorneyHash(g.Author, g.Attorney)
+}
+
+func putAttorneyGrant(grant AttorneyGrant, data *[]byte) {
+	util.PutToken(grant.Author, data)
+	util.PutToken(grant.Attorney, data)
+	util.PutUint64(grant.Expiry, data)
+	putScope(grant.Scope, data)
+	util.PutByte(grant.Depth, data)
+	util.PutToken(grant.Via, data)
+}
+
+func parseAttorneyGrant(data []byte, position int) (AttorneyGrant, int) {
+	grant := AttorneyGrant{}
+	grant.Author, position = util.ParseToken(data, position)
+	grant.Attorney, position = util.ParseToken(data, position)
+	grant.Expiry, position = util.ParseUint64(data, position)
+	grant.Scope, position = parseScope(data, position)
+	grant.Depth, position = util.ParseByte(data, position)
+	grant.Via, position = util.ParseToken(data, position)
+	return grant, position
+}
+
+func putAttorneyGrants(grants []AttorneyGrant, data *[]byte) {
+	util.PutUint32(uint32(len(grants)), data)
+	for _, grant := range grants {
+		putAttorneyGrant(grant, data)
+	}
+}
+
+func parseAttorneyGrants(data []byte, position int) ([]AttorneyGrant, int) {
+	count, position := util.ParseUint32(data, position)
+	if position > len(data) || int(count) > len(data)-position {
+		return nil, len(data) + 1
+	}
+	grants := make([]AttorneyGrant, count)
+	for n := range grants {
+		grants[n], position = parseAttorneyGrant(data, position)
+	}
+	return grants, position
+}
+
+var grantsCodec = keyedCodec[crypto.Token, []AttorneyGrant]{
+	putKey:     util.PutToken,
+	parseKey:   util.ParseToken,
+	putValue:   putAttorneyGrants,
+	parseValue: parseAttorneyGrants,
+}
+
+// grantStore indexes the powers of attorney granted by every member so that
+// they can be listed, which the hashes in the attorneys vault do not allow.
+// Grants are kept by author.
+type grantStore struct {
+	grants *keyedStore[crypto.Token, []AttorneyGrant]
+}
+
+// NewGrantStore creates an empty grant log at dataPath, discarding any
+// previous one. With an empty dataPath grants are kept only in memory.
+func NewGrantStore(name string, dataPath string) *grantStore {
+	grants := newKeyedStore(name, dataPath, grantsCodec)
+	if grants == nil {
+		return nil
+	}
+	return &grantStore{grants: grants}
+}
+
+// OpenGrantStore reopens an existing grant log at dataPath and replays it.
+func OpenGrantStore(name string, dataPath string) *grantStore {
+	grants := openKeyedStore(name, dataPath, grantsCodec)
+	if grants == nil {
+		return nil
+	}
+	return &grantStore{grants: grants}
+}
+
+func (g *grantStore) Get(author, attorney crypto.Token) (AttorneyGrant, bool) {
+	grants, _ := g.grants.Get(author)
+	for _, grant := range grants {
+		if grant.Attorney.Equal(attorney) {
+			return grant, true
+		}
+	}
+	return AttorneyGrant{}, false
+}
+
+// Granted returns every power of attorney granted by author.
+func (g *grantStore) Granted(author crypto.Token) []AttorneyGrant {
+	grants, _ := g.grants.Get(author)
+	return append([]AttorneyGrant{}, grants...)
+}
+
+// Grant records grant, replacing a previous grant of the author to the same
+// attorney. Grants of an author are kept in attorney order, so that the
+// stored list does not depend on the order grants were incorporated.
+func (g *grantStore) Grant(grant AttorneyGrant) bool {
+	grants := make([]AttorneyGrant, 0)
+	for _, existing := range g.Granted(grant.Author) {
+		if !existing.Attorney.Equal(grant.Attorney) {
+			grants = append(grants, existing)
+		}
+	}
+	grants = append(grants, grant)
+	sort.Slice(grants, func(i, j int) bool {
+		return bytes.Compare(grants[i].Attorney[:], grants[j].Attorney[:]) < 0
+	})
+	return g.grants.Set(grant.Author, grants)
+}
+
+func (g *grantStore) Revoke(author, attorney crypto.Token) bool {
+	existing := g.Granted(author)
+	grants := make([]AttorneyGrant, 0, len(existing))
+	for _, grant := range existing {
+		if !grant.Attorney.Equal(attorney) {
+			grants = append(grants, grant)
+		}
+	}
+	if len(grants) == len(existing) {
+		return false
+	}
+	if len(grants) == 0 {
+		return g.grants.Delete(author)
+	}
+	return g.grants.Set(author, grants)
+}
+
+// Checksum returns the accumulated commitment to every grant.
+func (g *grantStore) Checksum() crypto.Hash {
+	return g.grants.Checksum()
+}
+
+func (g *grantStore) Close() bool {
+	return g.grants.Close()
+}
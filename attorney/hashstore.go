@@ -51,8 +51,18 @@ func deleteOrInsert(found bool, hash crypto.Hash, b *papirus.Bucket, item int64,
 	}
 }
 
+// hashVault is a persistent set of hashes. Every change is also appended to
+// a journal, from which the checksum of the vault is rebuilt when it is
+// reopened.
 type hashVault struct {
-	hs *papirus.HashStore[crypto.Hash]
+	hs       *papirus.HashStore[crypto.Hash]
+	journal  *appendLog
+	checksum accumulator
+}
+
+// journalName is the name of the journal of the vault name.
+func journalName(name string) string {
+	return name + ".log"
 }
 
 func (w *hashVault) ExistsHash(hash crypto.Hash) bool {
@@ -69,6 +79,10 @@ func (w *hashVault) ExistsToken(token crypto.Token) bool {
 func (w *hashVault) InsertHash(hash crypto.Hash) bool {
 	response := make(chan papirus.QueryResult)
 	ok, _ := w.hs.Query(papirus.Query[crypto.Hash]{Hash: hash, Param: []byte{insert}, Response: response})
+	if ok {
+		w.checksum.Add(hash[:])
+		return w.journal.Append(append([]byte{insert}, hash[:]...))
+	}
 	return ok
 }
 
@@ -80,9 +94,18 @@ func (w *hashVault) InsertToken(token crypto.Token) bool {
 func (w *hashVault) RemoveHash(hash crypto.Hash) bool {
 	response := make(chan papirus.QueryResult)
 	ok, _ := w.hs.Query(papirus.Query[crypto.Hash]{Hash: hash, Param: []byte{remove}, Response: response})
+	if ok {
+		w.checksum.Remove(hash[:])
+		return w.journal.Append(append([]byte{remove}, hash[:]...))
+	}
 	return ok
 }
 
+// Checksum returns the accumulated commitment to the hashes in the vault.
+func (w *hashVault) Checksum() crypto.Hash {
+	return w.checksum.Digest()
+}
+
 func (w *hashVault) RemoveToken(token crypto.Token) bool {
 	hash := crypto.HashToken(token)
 	return w.RemoveHash(hash)
@@ -94,6 +117,9 @@ func (w *hashVault) Close() bool {
 			slog.Error("hashVault.Close", "msg", err)
 		}
 	}()
+	if w.journal != nil {
+		w.journal.Close()
+	}
 	ok := make(chan bool)
 	w.hs.Stop <- ok
 	return <-ok
@@ -122,10 +148,65 @@ func NewHashVault(name string, epoch uint64, bitsForBucket int64, dataPath strin
 		slog.Error("NewHashVault: NewBucketStore returned nil")
 		return nil
 	}
+	journal := newAppendLog(journalName(name), dataPath)
+	if journal == nil {
+		return nil
+	}
 	vault := &hashVault{
-		hs: papirus.NewHashStore(name, bucketstore, int(bitsForBucket), deleteOrInsert),
+		hs:      papirus.NewHashStore(name, bucketstore, int(bitsForBucket), deleteOrInsert),
+		journal: journal,
 	}
 	vault.hs.Start()
 	return vault
 
 }
+
+// OpenHashVault reopens the file store of a vault previously created with
+// NewHashVault at dataPath and rebuilds its checksum from its journal. visit,
+// if not nil, is called for every hash in the vault. It returns nil if a
+// hash of the journal is missing from the file store.
+func OpenHashVault(name string, bitsForBucket int64, dataPath string, visit func(crypto.Hash)) *hashVault {
+	bytestore := papirus.OpenFileStore(filepath.Join(dataPath, name))
+	if bytestore == nil {
+		slog.Error("OpenHashVault: OpenFileStore returned nil")
+		return nil
+	}
+	bucketstore := papirus.OpenBucketStore(bytestore)
+	if bucketstore == nil {
+		slog.Error("OpenHashVault: OpenBucketStore returned nil")
+		return nil
+	}
+	vault := &hashVault{
+		hs: papirus.NewHashStore(name, bucketstore, int(bitsForBucket), deleteOrInsert),
+	}
+	vault.hs.Start()
+	hashes := make(map[crypto.Hash]struct{})
+	vault.journal = openAppendLog(journalName(name), dataPath, func(record []byte) {
+		if len(record) != 1+crypto.Size {
+			return
+		}
+		var hash crypto.Hash
+		copy(hash[:], record[1:])
+		if record[0] == insert {
+			hashes[hash] = struct{}{}
+		} else {
+			delete(hashes, hash)
+		}
+	})
+	if vault.journal == nil {
+		vault.Close()
+		return nil
+	}
+	for hash := range hashes {
+		if !vault.ExistsHash(hash) {
+			slog.Error("OpenHashVault: journal does not match vault", "name", name)
+			vault.Close()
+			return nil
+		}
+		vault.checksum.Add(hash[:])
+		if visit != nil {
+			visit(hash)
+		}
+	}
+	return vault
+}
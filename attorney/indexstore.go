@@ -0,0 +1,219 @@
+package attorney
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/papirus"
+)
+
+const (
+	unset byte = iota
+	get
+	set
+)
+
+// readOrWrite is the papirus operation for index vaults. Items are the 32 byte
+// key hash followed by a fixed size value. param[0] is the operation and, for
+// set, param[1:] is the value to be stored.
+func readOrWrite(found bool, hash crypto.Hash, b *papirus.Bucket, item int64, param []byte) papirus.OperationResult {
+	if len(param) < 1 {
+		slog.Error("readOrWrite called with zero param length")
+		return papirus.OperationResult{
+			Result: papirus.QueryResult{Ok: false},
+		}
+	}
+	if found {
+		if param[0] == unset {
+			return papirus.OperationResult{
+				Deleted: &papirus.Item{Bucket: b, Item: item},
+				Result:  papirus.QueryResult{Ok: true},
+			}
+		} else if param[0] == get {
+			data := b.ReadItem(item)
+			return papirus.OperationResult{
+				Result: papirus.QueryResult{Ok: true, Data: data[crypto.Size:]},
+			}
+		} else { // overwrite
+			b.WriteItem(item, append(hash[:], param[1:]...))
+			return papirus.OperationResult{
+				Result: papirus.QueryResult{Ok: true},
+			}
+		}
+	} else {
+		if param[0] == set {
+			b.WriteItem(item, append(hash[:], param[1:]...))
+			return papirus.OperationResult{
+				Added:  &papirus.Item{Bucket: b, Item: item},
+				Result: papirus.QueryResult{Ok: true},
+			}
+		} else {
+			return papirus.OperationResult{
+				Result: papirus.QueryResult{Ok: false},
+			}
+		}
+	}
+}
+
+// indexVault is a persistent map from a hash to a fixed size value. The
+// checksum accumulates every key hash followed by its value. Every change is
+// also appended to a journal, from which the checksum is rebuilt when the
+// vault is reopened.
+type indexVault struct {
+	hs        *papirus.HashStore[crypto.Hash]
+	valueSize int
+	journal   *appendLog
+	checksum  accumulator
+}
+
+func (w *indexVault) Get(hash crypto.Hash) ([]byte, bool) {
+	response := make(chan papirus.QueryResult)
+	ok, data := w.hs.Query(papirus.Query[crypto.Hash]{Hash: hash, Param: []byte{get}, Response: response})
+	if !ok || len(data) < w.valueSize {
+		return nil, false
+	}
+	return data[:w.valueSize], true
+}
+
+// Set stores value under hash, replacing any previous value. Values shorter
+// than the vault value size are zero padded, longer ones are rejected.
+func (w *indexVault) Set(hash crypto.Hash, value []byte) bool {
+	if len(value) > w.valueSize {
+		slog.Error("indexVault.Set: value too large", "size", len(value), "max", w.valueSize)
+		return false
+	}
+	param := make([]byte, 1+w.valueSize)
+	param[0] = set
+	copy(param[1:], value)
+	previous, existed := w.Get(hash)
+	response := make(chan papirus.QueryResult)
+	ok, _ := w.hs.Query(papirus.Query[crypto.Hash]{Hash: hash, Param: param, Response: response})
+	if ok {
+		if existed {
+			w.checksum.Remove(append(hash[:], previous...))
+		}
+		w.checksum.Add(append(hash[:], param[1:]...))
+		return w.journal.Append(append([]byte{set}, append(hash[:], param[1:]...)...))
+	}
+	return ok
+}
+
+func (w *indexVault) Remove(hash crypto.Hash) bool {
+	previous, existed := w.Get(hash)
+	response := make(chan papirus.QueryResult)
+	ok, _ := w.hs.Query(papirus.Query[crypto.Hash]{Hash: hash, Param: []byte{unset}, Response: response})
+	if ok && existed {
+		w.checksum.Remove(append(hash[:], previous...))
+		return w.journal.Append(append([]byte{unset}, hash[:]...))
+	}
+	return ok
+}
+
+// Checksum returns the accumulated commitment to the entries in the vault.
+func (w *indexVault) Checksum() crypto.Hash {
+	return w.checksum.Digest()
+}
+
+func (w *indexVault) Close() bool {
+	defer func() {
+		if err := recover(); err != nil {
+			slog.Error("indexVault.Close", "msg", err)
+		}
+	}()
+	if w.journal != nil {
+		w.journal.Close()
+	}
+	ok := make(chan bool)
+	w.hs.Stop <- ok
+	return <-ok
+}
+
+func NewIndexVault(name string, epoch uint64, bitsForBucket int64, valueSize int, dataPath string) *indexVault {
+	itemSize := int64(crypto.Size + valueSize)
+	nbytes := 56 + (itemSize*6+8)*int64(1<<bitsForBucket)
+	var bytestore papirus.ByteStore
+	if dataPath == "" {
+		if store := papirus.NewMemoryStore(nbytes); store == nil {
+			slog.Error("NewIndexVault: NewMemoryStore returned nil")
+			return nil
+		} else {
+			bytestore = store
+		}
+	} else {
+		if store := papirus.NewFileStore(filepath.Join(dataPath, name), nbytes); store == nil {
+			slog.Error("NewIndexVault: NewFileStore returned nil")
+			return nil
+		} else {
+			bytestore = store
+		}
+	}
+	bucketstore := papirus.NewBucketStore(itemSize, 6, bytestore)
+	if bucketstore == nil {
+		slog.Error("NewIndexVault: NewBucketStore returned nil")
+		return nil
+	}
+	journal := newAppendLog(journalName(name), dataPath)
+	if journal == nil {
+		return nil
+	}
+	vault := &indexVault{
+		hs:        papirus.NewHashStore(name, bucketstore, int(bitsForBucket), readOrWrite),
+		valueSize: valueSize,
+		journal:   journal,
+	}
+	vault.hs.Start()
+	return vault
+}
+
+// OpenIndexVault reopens the file store of a vault previously created with
+// NewIndexVault at dataPath and rebuilds its checksum from its journal. visit,
+// if not nil, is called for every entry in the vault. It returns nil if an
+// entry of the journal is missing from the file store.
+func OpenIndexVault(name string, bitsForBucket int64, valueSize int, dataPath string, visit func(crypto.Hash, []byte)) *indexVault {
+	bytestore := papirus.OpenFileStore(filepath.Join(dataPath, name))
+	if bytestore == nil {
+		slog.Error("OpenIndexVault: OpenFileStore returned nil")
+		return nil
+	}
+	bucketstore := papirus.OpenBucketStore(bytestore)
+	if bucketstore == nil {
+		slog.Error("OpenIndexVault: OpenBucketStore returned nil")
+		return nil
+	}
+	vault := &indexVault{
+		hs:        papirus.NewHashStore(name, bucketstore, int(bitsForBucket), readOrWrite),
+		valueSize: valueSize,
+	}
+	vault.hs.Start()
+	entries := make(map[crypto.Hash][]byte)
+	vault.journal = openAppendLog(journalName(name), dataPath, func(record []byte) {
+		if len(record) < 1+crypto.Size {
+			return
+		}
+		var hash crypto.Hash
+		copy(hash[:], record[1:])
+		if record[0] == set && len(record) == 1+crypto.Size+valueSize {
+			entries[hash] = record[1+crypto.Size:]
+		} else if record[0] == unset {
+			delete(entries, hash)
+		}
+	})
+	if vault.journal == nil {
+		vault.Close()
+		return nil
+	}
+	for hash, value := range entries {
+		if stored, ok := vault.Get(hash); !ok || !bytes.Equal(stored, value) {
+			slog.Error("OpenIndexVault: journal does not match vault", "name", name)
+			vault.Close()
+			return nil
+		}
+		vault.checksum.Add(append(hash[:], value...))
+		if visit != nil {
+			visit(hash, value)
+		}
+	}
+	return vault
+}
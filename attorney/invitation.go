@@ -0,0 +1,252 @@
+package attorney
+
+import (
+	"encoding/json"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// Invitation lets Invitee join the network invited by the member Inviter. It
+// can be used once and, with a non zero Expiry, until that epoch. It lapses
+// if the inviter is no longer a member when it is used.
+type Invitation struct {
+	Inviter crypto.Token
+	Invitee crypto.Token
+	Expiry  uint64
+}
+
+// Hash identifies the invitation of Invitee by Inviter.
+func (i Invitation) Hash() crypto.Hash {
+	return invitationHash(i.Inviter, i.Invitee)
+}
+
+// ValidAt checks if the invitation has not expired at epoch.
+func (i Invitation) ValidAt(epoch uint64) bool {
+	return i.Expiry == 0 || epoch <= i.Expiry
+}
+
+func invitationHash(inviter, invitee crypto.Token) crypto.Hash {
+	return crypto.Hasher(append(inviter[:], invitee[:]...))
+}
+
+func putInvitation(invitation Invitation, data *[]byte) {
+	util.PutToken(invitation.Inviter, data)
+	util.PutToken(invitation.Invitee, data)
+	util.PutUint64(invitation.Expiry, data)
+}
+
+func parseInvitation(data []byte, position int) (Invitation, int) {
+	invitation := Invitation{}
+	invitation.Inviter, position = util.ParseToken(data, position)
+	invitation.Invitee, position = util.ParseToken(data, position)
+	invitation.Expiry, position = util.ParseUint64(data, position)
+	return invitation, position
+}
+
+var invitationCodec = keyedCodec[crypto.Hash, Invitation]{
+	putKey:     util.PutHash,
+	parseKey:   util.ParseHash,
+	putValue:   putInvitation,
+	parseValue: parseInvitation,
+}
+
+// Invite is an invitation issued by the member Author for Invitee to join the
+// network with JoinWithInvite. A non zero Expiry is the last epoch at which
+// it can be used and cannot be already past. Issuing it again replaces the
+// previous expiry.
+type Invite struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Invitee   crypto.Token
+	Expiry    uint64
+	Signature crypto.Signature
+}
+
+func (i *Invite) Tokens() []crypto.Token {
+	return []crypto.Token{i.Author, i.Invitee}
+}
+
+func (i *Invite) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(i.Author)
+	inviteeHash := crypto.HashToken(i.Invitee)
+	if !v.HasMember(i.Author) {
+		return reject(InviteType, NotMember, memberHash)
+	}
+	if v.HasMember(i.Invitee) {
+		return reject(InviteType, AlreadyMember, inviteeHash)
+	}
+	if v.IsRetired(i.Invitee) {
+		return reject(InviteType, RetiredToken, inviteeHash)
+	}
+	invitation := Invitation{Inviter: i.Author, Invitee: i.Invitee, Expiry: i.Expiry}
+	if !invitation.ValidAt(v.Epoch()) {
+		return reject(InviteType, Expired, invitation.Hash())
+	}
+	v.SetInvitation(invitation)
+	return accept(InviteType, memberHash, invitation.Hash())
+}
+
+func (i *Invite) Kind() byte {
+	return InviteType
+}
+
+func (i *Invite) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, i.Epoch, InviteType, i.Version)
+	util.PutToken(i.Author, &bytes)
+	util.PutToken(i.Invitee, &bytes)
+	util.PutUint64(i.Expiry, &bytes)
+	return bytes
+}
+
+func (i *Invite) Serialize() []byte {
+	return i.serialize(AxeProtocolCode)
+}
+
+func (i *Invite) serialize(protocol [4]byte) []byte {
+	bytes := i.serializeToSign(protocol)
+	util.PutSignature(i.Signature, &bytes)
+	return bytes
+}
+
+func (i *Invite) Sign(pk crypto.PrivateKey) {
+	i.sign(AxeProtocolCode, pk)
+}
+
+func (i *Invite) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := i.serializeToSign(protocol)
+	i.Signature = pk.Sign(bytes)
+}
+
+func ParseInvite(data []byte) *Invite {
+	invite, err := DecodeInvite(data)
+	if err != nil {
+		return nil
+	}
+	return invite
+}
+
+// DecodeInvite is like ParseInvite but returns the reason the action could
+// not be parsed.
+func DecodeInvite(data []byte) (*Invite, error) {
+	return decodeInvite(data, AxeProtocolCode)
+}
+
+func decodeInvite(data []byte, protocol [4]byte) (*Invite, error) {
+	var err error
+	invite := Invite{}
+	position := 0
+	if invite.Epoch, invite.Version, position, err = parseHeader(data, protocol, InviteType); err != nil {
+		return nil, err
+	}
+	invite.Author, position = util.ParseToken(data, position)
+	invite.Invitee, position = util.ParseToken(data, position)
+	invite.Expiry, position = util.ParseUint64(data, position)
+	if invite.Signature, err = parseTail(data, position, invite.Author); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// JoinWithInvite is a JoinNetwork using the invitation of Author by Inviter.
+// The invitation is consumed when the join is accepted.
+type JoinWithInvite struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Handle    string
+	Details   string
+	Inviter   crypto.Token
+	Signature crypto.Signature
+}
+
+func (j *JoinWithInvite) Tokens() []crypto.Token {
+	return []crypto.Token{j.Author, j.Inviter}
+}
+
+func (j *JoinWithInvite) Validate(v ActionValidator) Result {
+	hash := invitationHash(j.Inviter, j.Author)
+	invitation, ok := v.InvitationOf(hash)
+	if !ok || !v.HasMember(j.Inviter) {
+		return reject(JoinWithInviteType, NoInvitation, hash)
+	}
+	if !invitation.ValidAt(v.Epoch()) {
+		return reject(JoinWithInviteType, Expired, hash)
+	}
+	result := join(v, JoinWithInviteType, j.Author, j.Handle, j.Details)
+	if result.Accepted {
+		v.SetUsedInvitation(hash)
+		result.Hashes = append(result.Hashes, hash)
+	}
+	return result
+}
+
+func (j *JoinWithInvite) Kind() byte {
+	return JoinWithInviteType
+}
+
+func (j *JoinWithInvite) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, j.Epoch, JoinWithInviteType, j.Version)
+	util.PutToken(j.Author, &bytes)
+	util.PutString(j.Handle, &bytes)
+	util.PutString(j.Details, &bytes)
+	util.PutToken(j.Inviter, &bytes)
+	return bytes
+}
+
+func (j *JoinWithInvite) Serialize() []byte {
+	return j.serialize(AxeProtocolCode)
+}
+
+func (j *JoinWithInvite) serialize(protocol [4]byte) []byte {
+	bytes := j.serializeToSign(protocol)
+	util.PutSignature(j.Signature, &bytes)
+	return bytes
+}
+
+func (j *JoinWithInvite) Sign(key crypto.PrivateKey) {
+	j.sign(AxeProtocolCode, key)
+}
+
+func (j *JoinWithInvite) sign(protocol [4]byte, key crypto.PrivateKey) {
+	bytes := j.serializeToSign(protocol)
+	j.Signature = key.Sign(bytes)
+}
+
+func ParseJoinWithInvite(data []byte) *JoinWithInvite {
+	join, err := DecodeJoinWithInvite(data)
+	if err != nil {
+		return nil
+	}
+	return join
+}
+
+// DecodeJoinWithInvite is like ParseJoinWithInvite but returns the reason the
+// action could not be parsed.
+func DecodeJoinWithInvite(data []byte) (*JoinWithInvite, error) {
+	return decodeJoinWithInvite(data, AxeProtocolCode)
+}
+
+func decodeJoinWithInvite(data []byte, protocol [4]byte) (*JoinWithInvite, error) {
+	var err error
+	join := JoinWithInvite{}
+	position := 0
+	if join.Epoch, join.Version, position, err = parseHeader(data, protocol, JoinWithInviteType); err != nil {
+		return nil, err
+	}
+	join.Author, position = util.ParseToken(data, position)
+	join.Handle, position = util.ParseString(data, position)
+	join.Details, position = util.ParseString(data, position)
+	if position > len(data) {
+		return nil, ErrTruncated
+	}
+	if len(join.Details) > 0 && !json.Valid([]byte(join.Details)) {
+		return nil, ErrInvalidJSON
+	}
+	join.Inviter, position = util.ParseToken(data, position)
+	if join.Signature, err = parseTail(data, position, join.Author); err != nil {
+		return nil, err
+	}
+	return &join, nil
+}
@@ -0,0 +1,72 @@
+package attorney
+
+import (
+	"testing"
+)
+
+func signedInvite(epoch uint64, inviter testMember, invitee testMember, expiry uint64) *Invite {
+	action := &Invite{Epoch: epoch, Version: CurrentVersion, Author: inviter.token, Invitee: invitee.token, Expiry: expiry}
+	action.Sign(inviter.key)
+	return action
+}
+
+func signedJoinWithInvite(epoch uint64, member testMember, handle string, inviter testMember) *JoinWithInvite {
+	action := &JoinWithInvite{Epoch: epoch, Version: CurrentVersion, Author: member.token, Handle: handle, Details: "{}", Inviter: inviter.token}
+	action.Sign(member.key)
+	return action
+}
+
+// invitationState returns a state requiring invitations with founder as its
+// only member.
+func invitationState(t *testing.T, founder testMember) *State {
+	t.Helper()
+	config := DefaultConfig()
+	config.RequireInvitation = true
+	state := newTestState(t, config)
+	v := state.Validator(1)
+	v.SetNewMember(founder.token, "founder")
+	state.Incorporate(1, v.Mutations())
+	return state
+}
+
+func TestJoinRequiresInvitation(t *testing.T) {
+	founder, member, stranger := newMember(), newMember(), newMember()
+	state := invitationState(t, founder)
+	if result := check(state.Validator(2), member.key, signedJoin(2, member, "member")); result.Reason != InvitationRequired {
+		t.Errorf("joined without invitation: %v", result.Reason)
+	}
+	if result := check(state.Validator(2), member.key, signedJoinWithInvite(2, member, "member", founder)); result.Reason != NoInvitation {
+		t.Errorf("joined with a missing invitation: %v", result.Reason)
+	}
+	mustAccept(t, state, 2, founder.key, signedInvite(2, founder, member, 0))
+	if result := check(state.Validator(3), stranger.key, signedJoinWithInvite(3, stranger, "stranger", founder)); result.Reason != NoInvitation {
+		t.Errorf("joined with the invitation of another member: %v", result.Reason)
+	}
+	mustAccept(t, state, 3, member.key, signedJoinWithInvite(3, member, "member", founder))
+	if !state.HasMember(member.token) {
+		t.Fatal("invited member did not join")
+	}
+	if _, ok := state.InvitationOf(invitationHash(founder.token, member.token)); ok {
+		t.Error("invitation not consumed")
+	}
+}
+
+func TestInvitationExpiry(t *testing.T) {
+	founder, member := newMember(), newMember()
+	state := invitationState(t, founder)
+	if result := check(state.Validator(5), founder.key, signedInvite(5, founder, member, 4)); result.Reason != Expired {
+		t.Errorf("issued an expired invitation: %v", result.Reason)
+	}
+	mustAccept(t, state, 2, founder.key, signedInvite(2, founder, member, 3))
+	state.Incorporate(3, NewMutations())
+	if _, ok := state.InvitationOf(invitationHash(founder.token, member.token)); !ok {
+		t.Fatal("invitation pruned before its expiry")
+	}
+	if result := check(state.Validator(4), member.key, signedJoinWithInvite(4, member, "member", founder)); result.Accepted {
+		t.Error("joined with an expired invitation")
+	}
+	state.Incorporate(4, NewMutations())
+	if _, ok := state.InvitationOf(invitationHash(founder.token, member.token)); ok {
+		t.Error("expired invitation not pruned")
+	}
+}
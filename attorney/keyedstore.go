@@ -0,0 +1,145 @@
+package attorney
+
+import (
+	"sync"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+const (
+	deleteRecord byte = iota
+	setRecord
+)
+
+// keyedCodec serializes the keys and values of a keyedStore.
+type keyedCodec[K comparable, V any] struct {
+	putKey     func(K, *[]byte)
+	parseKey   func([]byte, int) (K, int)
+	putValue   func(V, *[]byte)
+	parseValue func([]byte, int) (V, int)
+}
+
+// keyedStore is an in memory map backed by an append only log of set and
+// delete records, for state that does not fit fixed size vault items. The
+// checksum accumulates the serialized key and value of every item and is
+// rebuilt when the log is replayed.
+type keyedStore[K comparable, V any] struct {
+	mu       sync.RWMutex
+	items    map[K]V
+	codec    keyedCodec[K, V]
+	log      *appendLog
+	checksum accumulator
+}
+
+// element is the serialized key and value of an item in the checksum.
+func (k *keyedStore[K, V]) element(key K, value V) []byte {
+	element := make([]byte, 0)
+	k.codec.putKey(key, &element)
+	k.codec.putValue(value, &element)
+	return element
+}
+
+// set and remove change the items and checksum without logging.
+func (k *keyedStore[K, V]) set(key K, value V) {
+	k.remove(key)
+	k.items[key] = value
+	k.checksum.Add(k.element(key, value))
+}
+
+func (k *keyedStore[K, V]) remove(key K) bool {
+	value, ok := k.items[key]
+	if !ok {
+		return false
+	}
+	delete(k.items, key)
+	k.checksum.Remove(k.element(key, value))
+	return true
+}
+
+func (k *keyedStore[K, V]) apply(record []byte) {
+	if len(record) < 1 {
+		return
+	}
+	key, position := k.codec.parseKey(record, 1)
+	if record[0] == deleteRecord {
+		if position <= len(record) {
+			k.remove(key)
+		}
+		return
+	}
+	value, position := k.codec.parseValue(record, position)
+	if position <= len(record) {
+		k.set(key, value)
+	}
+}
+
+// newKeyedStore creates an empty store logged at dataPath, discarding any
+// previous log. With an empty dataPath items are kept only in memory.
+func newKeyedStore[K comparable, V any](name, dataPath string, codec keyedCodec[K, V]) *keyedStore[K, V] {
+	log := newAppendLog(name, dataPath)
+	if log == nil {
+		return nil
+	}
+	return &keyedStore[K, V]{items: make(map[K]V), codec: codec, log: log}
+}
+
+// openKeyedStore reopens the store logged at dataPath and replays it.
+func openKeyedStore[K comparable, V any](name, dataPath string, codec keyedCodec[K, V]) *keyedStore[K, V] {
+	store := &keyedStore[K, V]{items: make(map[K]V), codec: codec}
+	if store.log = openAppendLog(name, dataPath, store.apply); store.log == nil {
+		return nil
+	}
+	return store
+}
+
+func (k *keyedStore[K, V]) Get(key K) (V, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	value, ok := k.items[key]
+	return value, ok
+}
+
+// Items returns a copy of every item in the store.
+func (k *keyedStore[K, V]) Items() map[K]V {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	items := make(map[K]V, len(k.items))
+	for key, value := range k.items {
+		items[key] = value
+	}
+	return items
+}
+
+func (k *keyedStore[K, V]) Set(key K, value V) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.set(key, value)
+	record := []byte{setRecord}
+	k.codec.putKey(key, &record)
+	k.codec.putValue(value, &record)
+	return k.log.Append(record)
+}
+
+func (k *keyedStore[K, V]) Delete(key K) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.remove(key) {
+		return false
+	}
+	record := []byte{deleteRecord}
+	k.codec.putKey(key, &record)
+	return k.log.Append(record)
+}
+
+// Checksum returns the accumulated commitment to the items in the store.
+func (k *keyedStore[K, V]) Checksum() crypto.Hash {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.checksum.Digest()
+}
+
+func (k *keyedStore[K, V]) Close() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.log.Close()
+}
@@ -7,18 +7,58 @@ import (
 )
 
 type Mutations struct {
-	GrantPower  map[crypto.Hash]struct{}
-	RevokePower map[crypto.Hash]struct{}
+	GrantPower  map[crypto.Hash]AttorneyGrant
+	RevokePower map[crypto.Hash]AttorneyGrant
 	NewMembers  map[crypto.Hash]struct{}
 	NewCaption  map[crypto.Hash]struct{}
+	// RemovedCaption are the hashes of handles released by renamed members.
+	RemovedCaption map[crypto.Hash]struct{}
+	NewHandles     map[crypto.Token]string
+	NewProfiles    map[crypto.Token]Profile
+	// Rotations maps the old token of a member to its new token.
+	Rotations map[crypto.Token]crypto.Token
+	// Leaving are the tokens of members leaving the network.
+	Leaving map[crypto.Token]struct{}
+	// NewThresholds are the threshold policies set by members. A zero
+	// threshold removes the policy.
+	NewThresholds map[crypto.Token]ThresholdPolicy
+	// NewGuardians are the guardians designated by members. A zero threshold
+	// removes the guardians.
+	NewGuardians map[crypto.Token]ThresholdPolicy
+	// NewRecoveries are the recoveries requested by guardians and
+	// CancelledRecoveries the tokens whose pending recovery was cancelled,
+	// with the epoch of the cancellation.
+	NewRecoveries       map[crypto.Token]Recovery
+	CancelledRecoveries map[crypto.Token]uint64
+	// NewProtocols are the protocol codes registered by members.
+	NewProtocols map[uint32]RegisteredProtocol
+	// SubProtocols are the pending changes of registered sub-protocols.
+	SubProtocols map[uint32]SubMutations
+	// NewInvitations are the invitations issued by members and
+	// UsedInvitations the hashes of invitations consumed by joins.
+	NewInvitations  map[crypto.Hash]Invitation
+	UsedInvitations map[crypto.Hash]struct{}
 }
 
 func NewMutations() *Mutations {
 	return &Mutations{
-		GrantPower:  make(map[crypto.Hash]struct{}),
-		RevokePower: make(map[crypto.Hash]struct{}),
-		NewMembers:  make(map[crypto.Hash]struct{}),
-		NewCaption:  make(map[crypto.Hash]struct{}),
+		GrantPower:          make(map[crypto.Hash]AttorneyGrant),
+		RevokePower:         make(map[crypto.Hash]AttorneyGrant),
+		NewMembers:          make(map[crypto.Hash]struct{}),
+		NewCaption:          make(map[crypto.Hash]struct{}),
+		RemovedCaption:      make(map[crypto.Hash]struct{}),
+		NewHandles:          make(map[crypto.Token]string),
+		NewProfiles:         make(map[crypto.Token]Profile),
+		Rotations:           make(map[crypto.Token]crypto.Token),
+		Leaving:             make(map[crypto.Token]struct{}),
+		NewThresholds:       make(map[crypto.Token]ThresholdPolicy),
+		NewGuardians:        make(map[crypto.Token]ThresholdPolicy),
+		NewRecoveries:       make(map[crypto.Token]Recovery),
+		CancelledRecoveries: make(map[crypto.Token]uint64),
+		NewProtocols:        make(map[uint32]RegisteredProtocol),
+		SubProtocols:        make(map[uint32]SubMutations),
+		NewInvitations:      make(map[crypto.Hash]Invitation),
+		UsedInvitations:     make(map[crypto.Hash]struct{}),
 	}
 }
 
@@ -58,19 +98,16 @@ func (m *Mutations) HasCaption(hash crypto.Hash) bool {
 	return ok
 }
 
+// Merge groups the receiver and others, in that order, into new mutations.
 func (m *Mutations) Merge(others ...*Mutations) *Mutations {
-	grouped := &Mutations{
-		GrantPower:  make(map[crypto.Hash]struct{}),
-		RevokePower: make(map[crypto.Hash]struct{}),
-		NewMembers:  make(map[crypto.Hash]struct{}),
-		NewCaption:  make(map[crypto.Hash]struct{}),
-	}
-	for _, mutations := range others {
-		for hash := range mutations.GrantPower {
-			grouped.GrantPower[hash] = struct{}{}
+	grouped := NewMutations()
+	for _, mutations := range append([]*Mutations{m}, others...) {
+		for hash, grant := range mutations.GrantPower {
+			grouped.GrantPower[hash] = grant
+			delete(grouped.RevokePower, hash)
 		}
-		for hash := range mutations.RevokePower {
-			grouped.RevokePower[hash] = struct{}{}
+		for hash, grant := range mutations.RevokePower {
+			grouped.RevokePower[hash] = grant
 			delete(grouped.GrantPower, hash)
 		}
 
@@ -78,8 +115,72 @@ func (m *Mutations) Merge(others ...*Mutations) *Mutations {
 			grouped.NewMembers[hash] = struct{}{}
 		}
 
+		for hash := range mutations.RemovedCaption {
+			grouped.RemovedCaption[hash] = struct{}{}
+			delete(grouped.NewCaption, hash)
+		}
+
 		for hash := range mutations.NewCaption {
 			grouped.NewCaption[hash] = struct{}{}
+			delete(grouped.RemovedCaption, hash)
+		}
+
+		for token, handle := range mutations.NewHandles {
+			grouped.NewHandles[token] = handle
+		}
+
+		for token, profile := range mutations.NewProfiles {
+			if existing, ok := grouped.NewProfiles[token]; !ok || existing.Epoch <= profile.Epoch {
+				grouped.NewProfiles[token] = profile
+			}
+		}
+
+		for old, token := range mutations.Rotations {
+			grouped.Rotations[old] = token
+		}
+
+		for token := range mutations.Leaving {
+			grouped.Leaving[token] = struct{}{}
+			delete(grouped.NewHandles, token)
+		}
+
+		for token, policy := range mutations.NewThresholds {
+			grouped.NewThresholds[token] = policy
+		}
+
+		for token, policy := range mutations.NewGuardians {
+			grouped.NewGuardians[token] = policy
+		}
+
+		for token, cancelled := range mutations.CancelledRecoveries {
+			grouped.CancelledRecoveries[token] = cancelled
+			delete(grouped.NewRecoveries, token)
+		}
+
+		for token, recovery := range mutations.NewRecoveries {
+			grouped.NewRecoveries[token] = recovery
+		}
+
+		for code, protocol := range mutations.NewProtocols {
+			grouped.NewProtocols[code] = protocol
+		}
+
+		for hash, invitation := range mutations.NewInvitations {
+			grouped.NewInvitations[hash] = invitation
+			delete(grouped.UsedInvitations, hash)
+		}
+
+		for hash := range mutations.UsedInvitations {
+			grouped.UsedInvitations[hash] = struct{}{}
+			delete(grouped.NewInvitations, hash)
+		}
+
+		for code, sub := range mutations.SubProtocols {
+			if existing, ok := grouped.SubProtocols[code]; ok {
+				grouped.SubProtocols[code] = existing.Merge(sub)
+			} else {
+				grouped.SubProtocols[code] = sub.Merge()
+			}
 		}
 	}
 	return grouped
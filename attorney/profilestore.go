@@ -0,0 +1,32 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// Profile is the latest member details and the epoch of the block that set
+// them.
+type Profile struct {
+	Epoch   uint64
+	Details string
+}
+
+func putProfile(profile Profile, data *[]byte) {
+	util.PutUint64(profile.Epoch, data)
+	util.PutString(profile.Details, data)
+}
+
+func parseProfile(data []byte, position int) (Profile, int) {
+	var profile Profile
+	profile.Epoch, position = util.ParseUint64(data, position)
+	profile.Details, position = util.ParseString(data, position)
+	return profile, position
+}
+
+var profileCodec = keyedCodec[crypto.Token, Profile]{
+	putKey:     util.PutToken,
+	parseKey:   util.ParseToken,
+	putValue:   putProfile,
+	parseValue: parseProfile,
+}
@@ -0,0 +1,150 @@
+package attorney
+
+import (
+	"encoding/json"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// MaxProtocolNameSize is the maximum length in bytes of a protocol name.
+const MaxProtocolNameSize = 64
+
+// RegisteredProtocol is a protocol code claimed by a member for the voids of
+// its social protocol. Epoch is the epoch of the block that registered it.
+type RegisteredProtocol struct {
+	Owner   crypto.Token
+	Name    string
+	Details string
+	Epoch   uint64
+}
+
+func putRegisteredProtocol(protocol RegisteredProtocol, data *[]byte) {
+	util.PutToken(protocol.Owner, data)
+	util.PutString(protocol.Name, data)
+	util.PutString(protocol.Details, data)
+	util.PutUint64(protocol.Epoch, data)
+}
+
+func parseRegisteredProtocol(data []byte, position int) (RegisteredProtocol, int) {
+	protocol := RegisteredProtocol{}
+	protocol.Owner, position = util.ParseToken(data, position)
+	protocol.Name, position = util.ParseString(data, position)
+	protocol.Details, position = util.ParseString(data, position)
+	protocol.Epoch, position = util.ParseUint64(data, position)
+	return protocol, position
+}
+
+var protocolCodec = keyedCodec[uint32, RegisteredProtocol]{
+	putKey:     util.PutUint32,
+	parseKey:   util.ParseUint32,
+	putValue:   putRegisteredProtocol,
+	parseValue: parseRegisteredProtocol,
+}
+
+// protocolCode returns the 4 byte code of protocol as carried by voids.
+func protocolCode(protocol uint32) [4]byte {
+	var code [4]byte
+	bytes := make([]byte, 0, 4)
+	util.PutUint32(protocol, &bytes)
+	copy(code[:], bytes)
+	return code
+}
+
+// RegisterProtocol claims Protocol for the voids of a social protocol named
+// Name and described by the JSON Details. Codes are claimed once and for all
+// and cannot be the code of the axé network itself.
+type RegisterProtocol struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Protocol  uint32
+	Name      string
+	Details   string
+	Signature crypto.Signature
+}
+
+func (r *RegisterProtocol) Tokens() []crypto.Token {
+	return []crypto.Token{r.Author}
+}
+
+func (r *RegisterProtocol) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(r.Author)
+	if !v.HasMember(r.Author) {
+		return reject(RegisterProtocolType, NotMember, memberHash)
+	}
+	if len(r.Name) > MaxProtocolNameSize {
+		return reject(RegisterProtocolType, ProtocolNameTooLong, memberHash)
+	}
+	protocol := RegisteredProtocol{Owner: r.Author, Name: r.Name, Details: r.Details, Epoch: v.Epoch()}
+	if !v.SetProtocol(r.Protocol, protocol) {
+		return reject(RegisterProtocolType, ProtocolTaken, memberHash)
+	}
+	return accept(RegisterProtocolType, memberHash)
+}
+
+func (r *RegisterProtocol) Kind() byte {
+	return RegisterProtocolType
+}
+
+func (r *RegisterProtocol) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, r.Epoch, RegisterProtocolType, r.Version)
+	util.PutToken(r.Author, &bytes)
+	util.PutUint32(r.Protocol, &bytes)
+	util.PutString(r.Name, &bytes)
+	util.PutString(r.Details, &bytes)
+	return bytes
+}
+
+func (r *RegisterProtocol) Serialize() []byte {
+	return r.serialize(AxeProtocolCode)
+}
+
+func (r *RegisterProtocol) serialize(protocol [4]byte) []byte {
+	bytes := r.serializeToSign(protocol)
+	util.PutSignature(r.Signature, &bytes)
+	return bytes
+}
+
+func (r *RegisterProtocol) Sign(pk crypto.PrivateKey) {
+	r.sign(AxeProtocolCode, pk)
+}
+
+func (r *RegisterProtocol) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := r.serializeToSign(protocol)
+	r.Signature = pk.Sign(bytes)
+}
+
+func ParseRegisterProtocol(data []byte) *RegisterProtocol {
+	register, err := DecodeRegisterProtocol(data)
+	if err != nil {
+		return nil
+	}
+	return register
+}
+
+// DecodeRegisterProtocol is like ParseRegisterProtocol but returns the reason
+// the action could not be parsed.
+func DecodeRegisterProtocol(data []byte) (*RegisterProtocol, error) {
+	return decodeRegisterProtocol(data, AxeProtocolCode)
+}
+
+func decodeRegisterProtocol(data []byte, protocol [4]byte) (*RegisterProtocol, error) {
+	var err error
+	register := RegisterProtocol{}
+	position := 0
+	if register.Epoch, register.Version, position, err = parseHeader(data, protocol, RegisterProtocolType); err != nil {
+		return nil, err
+	}
+	register.Author, position = util.ParseToken(data, position)
+	register.Protocol, position = util.ParseUint32(data, position)
+	register.Name, position = util.ParseString(data, position)
+	register.Details, position = util.ParseString(data, position)
+	if len(register.Details) > 0 && !json.Valid([]byte(register.Details)) {
+		return nil, ErrInvalidJSON
+	}
+	if register.Signature, err = parseTail(data, position, register.Author); err != nil {
+		return nil, err
+	}
+	return &register, nil
+}
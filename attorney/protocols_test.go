@@ -0,0 +1,28 @@
+package attorney
+
+import (
+	"testing"
+)
+
+func TestRegisterProtocolAtBlockEpoch(t *testing.T) {
+	state := newTestState(t, DefaultConfig())
+	owner := newMember()
+	mustAccept(t, state, 1, owner.key, signedJoin(1, owner, "owner"))
+	register := &RegisterProtocol{Epoch: 1, Version: CurrentVersion, Author: owner.token, Protocol: 42, Name: "synergy", Details: "{}"}
+	register.Sign(owner.key)
+	mustAccept(t, state, 5, owner.key, register)
+	protocol, ok := state.ProtocolOf(42)
+	if !ok || !protocol.Owner.Equal(owner.token) {
+		t.Fatal("protocol not registered")
+	}
+	if protocol.Epoch != 5 {
+		t.Errorf("protocol registered at epoch %d", protocol.Epoch)
+	}
+	other := newMember()
+	mustAccept(t, state, 6, other.key, signedJoin(6, other, "other"))
+	register = &RegisterProtocol{Epoch: 6, Version: CurrentVersion, Author: other.token, Protocol: 42, Name: "taken"}
+	register.Sign(other.key)
+	if result := check(state.Validator(7), other.key, register); result.Reason != ProtocolTaken {
+		t.Errorf("registered a taken protocol code: %v", result.Reason)
+	}
+}
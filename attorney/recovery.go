@@ -0,0 +1,336 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// DefaultRecoveryDelay is the number of epochs a recovery waits before it
+// reassigns an identity, about a day at one block per second.
+const DefaultRecoveryDelay uint64 = 86400
+
+// Recovery is a pending reassignment of an identity to NewToken that takes
+// effect at the Unlock epoch unless cancelled by the original key.
+type Recovery struct {
+	NewToken crypto.Token
+	Unlock   uint64
+}
+
+func putRecovery(recovery Recovery, data *[]byte) {
+	util.PutToken(recovery.NewToken, data)
+	util.PutUint64(recovery.Unlock, data)
+}
+
+func parseRecovery(data []byte, position int) (Recovery, int) {
+	recovery := Recovery{}
+	recovery.NewToken, position = util.ParseToken(data, position)
+	recovery.Unlock, position = util.ParseUint64(data, position)
+	return recovery, position
+}
+
+var recoveryCodec = keyedCodec[crypto.Token, Recovery]{
+	putKey:     util.PutToken,
+	parseKey:   util.ParseToken,
+	putValue:   putRecovery,
+	parseValue: parseRecovery,
+}
+
+var cancellationCodec = keyedCodec[crypto.Token, uint64]{
+	putKey:     util.PutToken,
+	parseKey:   util.ParseToken,
+	putValue:   util.PutUint64,
+	parseValue: util.ParseUint64,
+}
+
+// SetGuardians designates the guardians of Author and how many of them must
+// cosign a recovery of its identity. A zero Threshold removes the guardians.
+type SetGuardians struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Threshold byte
+	Guardians []crypto.Token
+	Signature crypto.Signature
+}
+
+func (g *SetGuardians) Tokens() []crypto.Token {
+	return append([]crypto.Token{g.Author}, g.Guardians...)
+}
+
+func (g *SetGuardians) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(g.Author)
+	if !v.HasMember(g.Author) {
+		return reject(SetGuardiansType, NotMember, memberHash)
+	}
+	if !thresholdApproved(v, g.Author) {
+		return reject(SetGuardiansType, ThresholdNotMet, memberHash)
+	}
+	policy := ThresholdPolicy{Threshold: g.Threshold, Signers: g.Guardians}
+	if !policy.Valid() {
+		return reject(SetGuardiansType, InvalidThreshold, memberHash)
+	}
+	v.SetGuardians(g.Author, policy)
+	return accept(SetGuardiansType, memberHash)
+}
+
+func (g *SetGuardians) Kind() byte {
+	return SetGuardiansType
+}
+
+func (g *SetGuardians) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, g.Epoch, SetGuardiansType, g.Version)
+	util.PutToken(g.Author, &bytes)
+	putThresholdPolicy(ThresholdPolicy{Threshold: g.Threshold, Signers: g.Guardians}, &bytes)
+	return bytes
+}
+
+func (g *SetGuardians) Serialize() []byte {
+	return g.serialize(AxeProtocolCode)
+}
+
+func (g *SetGuardians) serialize(protocol [4]byte) []byte {
+	bytes := g.serializeToSign(protocol)
+	util.PutSignature(g.Signature, &bytes)
+	return bytes
+}
+
+func (g *SetGuardians) Sign(pk crypto.PrivateKey) {
+	g.sign(AxeProtocolCode, pk)
+}
+
+func (g *SetGuardians) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := g.serializeToSign(protocol)
+	g.Signature = pk.Sign(bytes)
+}
+
+func ParseSetGuardians(data []byte) *SetGuardians {
+	guardians, err := DecodeSetGuardians(data)
+	if err != nil {
+		return nil
+	}
+	return guardians
+}
+
+// DecodeSetGuardians is like ParseSetGuardians but returns the reason the
+// action could not be parsed.
+func DecodeSetGuardians(data []byte) (*SetGuardians, error) {
+	return decodeSetGuardians(data, AxeProtocolCode)
+}
+
+func decodeSetGuardians(data []byte, protocol [4]byte) (*SetGuardians, error) {
+	var err error
+	var policy ThresholdPolicy
+	guardians := SetGuardians{}
+	position := 0
+	if guardians.Epoch, guardians.Version, position, err = parseHeader(data, protocol, SetGuardiansType); err != nil {
+		return nil, err
+	}
+	guardians.Author, position = util.ParseToken(data, position)
+	policy, position = parseThresholdPolicy(data, position)
+	guardians.Threshold, guardians.Guardians = policy.Threshold, policy.Signers
+	if guardians.Signature, err = parseTail(data, position, guardians.Author); err != nil {
+		return nil, err
+	}
+	return &guardians, nil
+}
+
+// RequestRecovery asks to reassign the identity of Author to NewToken. It must
+// be cosigned by NewToken and by enough guardians of Author. The identity is
+// rotated to NewToken once the recovery delay of the network has elapsed,
+// unless Author cancels it first. The request is bound to its Epoch: once
+// Author cancels a recovery, requests signed for that epoch or earlier are
+// rejected.
+type RequestRecovery struct {
+	Epoch      uint64
+	Version    byte
+	Author     crypto.Token
+	NewToken   crypto.Token
+	Signers    []crypto.Token
+	Signatures []crypto.Signature
+}
+
+func (r *RequestRecovery) Tokens() []crypto.Token {
+	return append([]crypto.Token{r.Author, r.NewToken}, r.Signers...)
+}
+
+func (r *RequestRecovery) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(r.Author)
+	newHash := crypto.HashToken(r.NewToken)
+	if r.Epoch > v.Epoch() {
+		return reject(RequestRecoveryType, FutureEpoch)
+	}
+	if !v.HasMember(r.Author) {
+		return reject(RequestRecoveryType, NotMember, memberHash)
+	}
+	if cancelled, ok := v.RecoveryCancelledAt(r.Author); ok && r.Epoch <= cancelled {
+		return reject(RequestRecoveryType, CancelledRecovery, memberHash)
+	}
+	if v.IsRetired(r.NewToken) {
+		return reject(RequestRecoveryType, RetiredToken, newHash)
+	}
+	if v.HasMember(r.NewToken) {
+		return reject(RequestRecoveryType, AlreadyMember, newHash)
+	}
+	guardians, ok := v.GuardiansOf(r.Author)
+	if !ok {
+		return reject(RequestRecoveryType, NoGuardians, memberHash)
+	}
+	if !guardians.Met(v, r.Signers) {
+		return reject(RequestRecoveryType, ThresholdNotMet, memberHash)
+	}
+	if !v.SetRecovery(r.Author, r.NewToken) {
+		return reject(RequestRecoveryType, PendingRecovery, memberHash)
+	}
+	return accept(RequestRecoveryType, memberHash, newHash)
+}
+
+func (r *RequestRecovery) Kind() byte {
+	return RequestRecoveryType
+}
+
+func (r *RequestRecovery) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, r.Epoch, RequestRecoveryType, r.Version)
+	util.PutToken(r.Author, &bytes)
+	util.PutToken(r.NewToken, &bytes)
+	return bytes
+}
+
+func (r *RequestRecovery) Serialize() []byte {
+	return r.serialize(AxeProtocolCode)
+}
+
+func (r *RequestRecovery) serialize(protocol [4]byte) []byte {
+	bytes := r.serializeToSign(protocol)
+	putCosigners(r.Signers, r.Signatures, &bytes)
+	return bytes
+}
+
+// Sign adds the signature of pk, either NewToken or a guardian, to the
+// request.
+func (r *RequestRecovery) Sign(pk crypto.PrivateKey) {
+	r.sign(AxeProtocolCode, pk)
+}
+
+func (r *RequestRecovery) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := r.serializeToSign(protocol)
+	r.Signers = append(r.Signers, pk.PublicKey())
+	r.Signatures = append(r.Signatures, pk.Sign(bytes))
+}
+
+func ParseRequestRecovery(data []byte) *RequestRecovery {
+	request, err := DecodeRequestRecovery(data)
+	if err != nil {
+		return nil
+	}
+	return request
+}
+
+// DecodeRequestRecovery is like ParseRequestRecovery but returns the reason
+// the action could not be parsed. NewToken must be among the signers.
+func DecodeRequestRecovery(data []byte) (*RequestRecovery, error) {
+	return decodeRequestRecovery(data, AxeProtocolCode)
+}
+
+func decodeRequestRecovery(data []byte, protocol [4]byte) (*RequestRecovery, error) {
+	var err error
+	request := RequestRecovery{}
+	position := 0
+	if request.Epoch, request.Version, position, err = parseHeader(data, protocol, RequestRecoveryType); err != nil {
+		return nil, err
+	}
+	request.Author, position = util.ParseToken(data, position)
+	request.NewToken, position = util.ParseToken(data, position)
+	if request.Signers, request.Signatures, err = parseCosigners(data, position); err != nil {
+		return nil, err
+	}
+	for _, signer := range request.Signers {
+		if signer.Equal(request.NewToken) {
+			return &request, nil
+		}
+	}
+	return nil, ErrBadSignature
+}
+
+// CancelRecovery cancels the pending recovery of the identity of Author. It
+// must be signed by Author itself.
+type CancelRecovery struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Signature crypto.Signature
+}
+
+func (c *CancelRecovery) Tokens() []crypto.Token {
+	return []crypto.Token{c.Author}
+}
+
+func (c *CancelRecovery) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(c.Author)
+	if !v.HasMember(c.Author) {
+		return reject(CancelRecoveryType, NotMember, memberHash)
+	}
+	if !thresholdApproved(v, c.Author) {
+		return reject(CancelRecoveryType, ThresholdNotMet, memberHash)
+	}
+	if !v.SetCancelRecovery(c.Author) {
+		return reject(CancelRecoveryType, NoRecovery, memberHash)
+	}
+	return accept(CancelRecoveryType, memberHash)
+}
+
+func (c *CancelRecovery) Kind() byte {
+	return CancelRecoveryType
+}
+
+func (c *CancelRecovery) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, c.Epoch, CancelRecoveryType, c.Version)
+	util.PutToken(c.Author, &bytes)
+	return bytes
+}
+
+func (c *CancelRecovery) Serialize() []byte {
+	return c.serialize(AxeProtocolCode)
+}
+
+func (c *CancelRecovery) serialize(protocol [4]byte) []byte {
+	bytes := c.serializeToSign(protocol)
+	util.PutSignature(c.Signature, &bytes)
+	return bytes
+}
+
+func (c *CancelRecovery) Sign(pk crypto.PrivateKey) {
+	c.sign(AxeProtocolCode, pk)
+}
+
+func (c *CancelRecovery) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := c.serializeToSign(protocol)
+	c.Signature = pk.Sign(bytes)
+}
+
+func ParseCancelRecovery(data []byte) *CancelRecovery {
+	cancel, err := DecodeCancelRecovery(data)
+	if err != nil {
+		return nil
+	}
+	return cancel
+}
+
+// DecodeCancelRecovery is like ParseCancelRecovery but returns the reason the
+// action could not be parsed.
+func DecodeCancelRecovery(data []byte) (*CancelRecovery, error) {
+	return decodeCancelRecovery(data, AxeProtocolCode)
+}
+
+func decodeCancelRecovery(data []byte, protocol [4]byte) (*CancelRecovery, error) {
+	var err error
+	cancel := CancelRecovery{}
+	position := 0
+	if cancel.Epoch, cancel.Version, position, err = parseHeader(data, protocol, CancelRecoveryType); err != nil {
+		return nil, err
+	}
+	cancel.Author, position = util.ParseToken(data, position)
+	if cancel.Signature, err = parseTail(data, position, cancel.Author); err != nil {
+		return nil, err
+	}
+	return &cancel, nil
+}
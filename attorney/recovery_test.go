@@ -0,0 +1,93 @@
+package attorney
+
+import (
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+func signedRecovery(epoch uint64, author testMember, member testMember, guardians ...testMember) *RequestRecovery {
+	action := &RequestRecovery{Epoch: epoch, Version: CurrentVersion, Author: author.token, NewToken: member.token}
+	action.Sign(member.key)
+	for _, guardian := range guardians {
+		action.Sign(guardian.key)
+	}
+	return action
+}
+
+// guardedState returns a state with a recovery delay of two epochs where
+// author is guarded by any one of guardians. Every guardian is a member.
+func guardedState(t *testing.T, author testMember, guardians ...testMember) *State {
+	t.Helper()
+	config := DefaultConfig()
+	config.RecoveryDelay = 2
+	state := newTestState(t, config)
+	actions := []Action{signedJoin(1, author, "author")}
+	tokens := make([]crypto.Token, 0, len(guardians))
+	for n, guardian := range guardians {
+		actions = append(actions, signedJoin(1, guardian, string(rune('a'+n))))
+		tokens = append(tokens, guardian.token)
+	}
+	mustAccept(t, state, 1, author.key, actions...)
+	policy := &SetGuardians{Epoch: 2, Version: CurrentVersion, Author: author.token, Threshold: 1, Guardians: tokens}
+	policy.Sign(author.key)
+	mustAccept(t, state, 2, author.key, policy)
+	return state
+}
+
+func TestRecoveryUnlocks(t *testing.T) {
+	author, guardian, recovered := newMember(), newMember(), newMember()
+	state := guardedState(t, author, guardian)
+	mustAccept(t, state, 3, guardian.key, signedRecovery(3, author, recovered, guardian))
+	state.Incorporate(4, NewMutations())
+	if !state.HasMember(author.token) {
+		t.Fatal("identity recovered before the recovery delay")
+	}
+	state.Incorporate(5, NewMutations())
+	if state.HasMember(author.token) || !state.IsRetired(author.token) {
+		t.Error("recovered token was not retired")
+	}
+	if handle, _ := state.HandleOf(recovered.token); !state.HasMember(recovered.token) || handle != "author" {
+		t.Error("identity was not moved to the recovered token")
+	}
+}
+
+func TestRecoveryIgnoresRetiredGuardians(t *testing.T) {
+	author, guardian, recovered := newMember(), newMember(), newMember()
+	state := guardedState(t, author, guardian)
+	mustAccept(t, state, 3, guardian.key, signedRotation(3, guardian, newMember()))
+	if result := check(state.Validator(4), guardian.key, signedRecovery(4, author, recovered, guardian)); result.Reason != ThresholdNotMet {
+		t.Errorf("recovery approved by a retired guardian: %v", result.Reason)
+	}
+}
+
+func TestCancelledRecoveryCannotBeReplayed(t *testing.T) {
+	author, guardian, recovered := newMember(), newMember(), newMember()
+	state := guardedState(t, author, guardian)
+	request := signedRecovery(3, author, recovered, guardian)
+	mustAccept(t, state, 3, guardian.key, request)
+	cancel := &CancelRecovery{Epoch: 4, Version: CurrentVersion, Author: author.token}
+	cancel.Sign(author.key)
+	mustAccept(t, state, 4, author.key, cancel)
+	if result := check(state.Validator(5), guardian.key, request); result.Reason != CancelledRecovery {
+		t.Errorf("cancelled recovery replayed: %v", result.Reason)
+	}
+	if result := check(state.Validator(5), guardian.key, signedRecovery(5, author, recovered, guardian)); !result.Accepted {
+		t.Errorf("new recovery after cancellation rejected: %v", result.Reason)
+	}
+
+	// a request cancelled within the block cannot be submitted again in it
+	v := state.Validator(5)
+	again := signedRecovery(5, author, recovered, guardian)
+	if result := check(v, guardian.key, again); !result.Accepted {
+		t.Fatalf("recovery rejected: %v", result.Reason)
+	}
+	cancel = &CancelRecovery{Epoch: 5, Version: CurrentVersion, Author: author.token}
+	cancel.Sign(author.key)
+	if result := check(v, author.key, cancel); !result.Accepted {
+		t.Fatalf("cancellation rejected: %v", result.Reason)
+	}
+	if result := check(v, guardian.key, again); result.Reason != CancelledRecovery {
+		t.Errorf("recovery cancelled in the block replayed: %v", result.Reason)
+	}
+}
@@ -0,0 +1,143 @@
+package attorney
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// Reason tells why an axé action was accepted or rejected by validation.
+type Reason byte
+
+const (
+	Accepted Reason = iota
+	Unparseable
+	HandleTaken
+	HandleTooLong
+	AlreadyMember
+	NotMember
+	NoPowerOfAttorney
+	AlreadyAttorney
+	FutureEpoch
+	RetiredToken
+	PendingRotation
+	PendingLeave
+	Expired
+	DelegationNotAllowed
+	DelegationTooDeep
+	ScopeExceeded
+	InvalidThreshold
+	ThresholdNotMet
+	NoGuardians
+	PendingRecovery
+	NoRecovery
+	ProtocolTaken
+	ProtocolNameTooLong
+	UnregisteredProtocol
+	SubProtocolRejected
+	InvitationRequired
+	NoInvitation
+	BlockJoinLimit
+	WalletJoinLimit
+	ForeignInstruction
+	CancelledRecovery
+)
+
+var reasonNames = map[Reason]string{
+	Accepted:             "accepted",
+	Unparseable:          "unparseable",
+	HandleTaken:          "handle taken",
+	HandleTooLong:        "handle too long",
+	AlreadyMember:        "already member",
+	NotMember:            "not member",
+	NoPowerOfAttorney:    "no power of attorney",
+	AlreadyAttorney:      "already attorney",
+	FutureEpoch:          "future epoch",
+	RetiredToken:         "retired token",
+	PendingRotation:      "pending rotation",
+	PendingLeave:         "pending leave",
+	Expired:              "expired",
+	DelegationNotAllowed: "delegation not allowed",
+	DelegationTooDeep:    "delegation too deep",
+	ScopeExceeded:        "scope exceeded",
+	InvalidThreshold:     "invalid threshold",
+	ThresholdNotMet:      "threshold not met",
+	NoGuardians:          "no guardians",
+	PendingRecovery:      "pending recovery",
+	NoRecovery:           "no recovery",
+	ProtocolTaken:        "protocol taken",
+	ProtocolNameTooLong:  "protocol name too long",
+	UnregisteredProtocol: "unregistered protocol",
+	SubProtocolRejected:  "rejected by sub-protocol",
+	InvitationRequired:   "invitation required",
+	NoInvitation:         "no invitation",
+	BlockJoinLimit:       "block join limit reached",
+	WalletJoinLimit:      "wallet join limit reached",
+	ForeignInstruction:   "instruction of another author",
+	CancelledRecovery:    "cancelled recovery",
+}
+
+func (r Reason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Result is the outcome of the validation of an axé action. Hashes are the
+// state hashes touched by the action: member, caption or attorney hashes.
+type Result struct {
+	Accepted bool
+	Kind     byte
+	Reason   Reason
+	Hashes   []crypto.Hash
+	// Err is the parse error of unparseable actions.
+	Err error
+}
+
+func accept(kind byte, hashes ...crypto.Hash) Result {
+	return Result{Accepted: true, Kind: kind, Reason: Accepted, Hashes: hashes}
+}
+
+func reject(kind byte, reason Reason, hashes ...crypto.Hash) Result {
+	return Result{Accepted: false, Kind: kind, Reason: reason, Hashes: hashes}
+}
+
+func attorneyHash(token, attorney crypto.Token) crypto.Hash {
+	return crypto.Hasher(append(token[:], attorney[:]...))
+}
+
+// validationLog configures how validation results are logged.
+type validationLog struct {
+	logger   *slog.Logger
+	accepted slog.Level
+	rejected slog.Level
+}
+
+func defaultValidationLog() validationLog {
+	return validationLog{
+		logger:   slog.Default(),
+		accepted: slog.LevelDebug,
+		rejected: slog.LevelInfo,
+	}
+}
+
+func (l validationLog) log(result Result) {
+	logger := l.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := l.accepted
+	if !result.Accepted {
+		level = l.rejected
+	}
+	attrs := []any{"kind", result.Kind, "accepted", result.Accepted, "reason", result.Reason.String()}
+	for _, hash := range result.Hashes {
+		attrs = append(attrs, "hash", hash.String())
+	}
+	if result.Err != nil {
+		attrs = append(attrs, "error", result.Err)
+	}
+	logger.Log(context.Background(), level, "axe validation", attrs...)
+}
@@ -1,73 +1,700 @@
 package attorney
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
 	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// MaxHandleSize is the maximum length in bytes of a member handle.
+const MaxHandleSize = 64
+
+const checkpointFile = "checkpoint"
+
+// incorporatingFile marks on dataPath the epoch being incorporated until its
+// checkpoint is written.
+const incorporatingFile = "incorporating"
+
+// stateFiles are the files on dataPath making up a persisted state.
+var stateFiles = []string{"members", "captions", "poa", "retired", "handles", "tokens",
+	journalName("members"), journalName("captions"), journalName("poa"), journalName("retired"), journalName("handles"), journalName("tokens"), "profiles", "grants", "thresholds", "guardians", "recoveries", "cancellations", "protocols", "invitations", checkpointFile}
+
+// HandlePolicy decides what happens to the handle of a member leaving the
+// network.
+type HandlePolicy byte
+
+const (
+	// TombstoneHandle keeps the handle reserved so it can never be claimed
+	// again.
+	TombstoneHandle HandlePolicy = iota
+	// FreeHandle releases the handle to be claimed by anyone.
+	FreeHandle
 )
 
 type State struct {
+	// Epoch is the epoch of the last incorporated mutations.
+	Epoch     uint64
+	dataPath  string
 	Members   *hashVault
 	Captions  *hashVault
 	Attorneys *hashVault
+	// Retired holds the hashes of tokens replaced by a key rotation.
+	Retired *hashVault
+	// Handles maps the hash of a handle to the token of its owner, and
+	// Tokens maps the hash of a token to the handle of the member.
+	Handles *indexVault
+	Tokens  *indexVault
+	// Profiles holds the latest details of every member.
+	Profiles *keyedStore[crypto.Token, Profile]
+	// Grants lists the powers of attorney granted by every member.
+	Grants *grantStore
+	// Thresholds holds the threshold policies of members.
+	Thresholds *keyedStore[crypto.Token, ThresholdPolicy]
+	// Guardians holds the guardians of members and Recoveries the pending
+	// recoveries of their identities. Cancellations holds the epoch at
+	// which each member last cancelled a recovery, so that a cancelled
+	// request cannot be submitted again.
+	Guardians     *keyedStore[crypto.Token, ThresholdPolicy]
+	Recoveries    *keyedStore[crypto.Token, Recovery]
+	Cancellations *keyedStore[crypto.Token, uint64]
+	// Protocols is the registry of protocol codes claimed for voids.
+	Protocols *keyedStore[uint32, RegisteredProtocol]
+	// Invitations holds the unused invitations issued by members.
+	Invitations *keyedStore[crypto.Hash, Invitation]
+
+	config        Config
+	subProtocols  map[uint32]SubProtocol
+	codec         *Codec
+	validationLog validationLog
 }
 
-func NewGenesisState(dataPath string) *State {
+// NewGenesisState creates an empty state on dataPath, discarding any state
+// previously persisted there, for an axé network with the given config.
+func NewGenesisState(dataPath string, config Config) *State {
 	state := State{
-		Members:   NewHashVault("members", 0, 8, dataPath),
-		Captions:  NewHashVault("captions", 0, 8, dataPath),
-		Attorneys: NewHashVault("poa", 0, 8, dataPath),
+		Members:       NewHashVault("members", 0, 8, dataPath),
+		Captions:      NewHashVault("captions", 0, 8, dataPath),
+		Attorneys:     NewHashVault("poa", 0, 8, dataPath),
+		Retired:       NewHashVault("retired", 0, 8, dataPath),
+		Handles:       NewIndexVault("handles", 0, 8, crypto.TokenSize, dataPath),
+		Tokens:        NewIndexVault("tokens", 0, 8, 1+MaxHandleSize, dataPath),
+		Profiles:      newKeyedStore("profiles", dataPath, profileCodec),
+		Grants:        NewGrantStore("grants", dataPath),
+		Thresholds:    newKeyedStore("thresholds", dataPath, thresholdCodec),
+		Guardians:     newKeyedStore("guardians", dataPath, thresholdCodec),
+		Recoveries:    newKeyedStore("recoveries", dataPath, recoveryCodec),
+		Cancellations: newKeyedStore("cancellations", dataPath, cancellationCodec),
+		Protocols:     newKeyedStore("protocols", dataPath, protocolCodec),
+		Invitations:   newKeyedStore("invitations", dataPath, invitationCodec),
+		dataPath:      dataPath,
+		config:        config,
+		codec:         NewCodec(config.Protocol),
+		validationLog: defaultValidationLog(),
 	}
+	if !state.opened() {
+		slog.Error("NewGenesisState: could not create axe state vaults", "dataPath", dataPath)
+		state.Shutdown()
+		return nil
+	}
+	state.checkpoint()
 	return &state
 }
 
-func (s *State) Validator(mutations ...*Mutations) *MutatingState {
+// opened checks that every store of the state was created or reopened.
+func (s *State) opened() bool {
+	return s.Members != nil && s.Captions != nil && s.Attorneys != nil && s.Retired != nil && s.Handles != nil && s.Tokens != nil && s.Profiles != nil && s.Grants != nil && s.Thresholds != nil && s.Guardians != nil && s.Recoveries != nil && s.Cancellations != nil && s.Protocols != nil && s.Invitations != nil
+}
+
+// RecoverState reopens the state persisted on dataPath by a previous node of
+// the axé network with the given config.
+func RecoverState(dataPath string, config Config) (*State, error) {
+	state := State{dataPath: dataPath, config: config, codec: NewCodec(config.Protocol), validationLog: defaultValidationLog()}
+	if err := state.Recover(); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func encodeHandle(handle string) []byte {
+	return append([]byte{byte(len(handle))}, handle...)
+}
+
+func decodeHandle(data []byte) (string, bool) {
+	if len(data) < 1 || int(data[0]) > len(data)-1 {
+		return "", false
+	}
+	return string(data[1 : 1+int(data[0])]), true
+}
+
+// Validator returns a mutating state to validate the actions of a block at
+// the given epoch on top of the state and the provided mutations.
+func (s *State) Validator(epoch uint64, mutations ...*Mutations) *MutatingState {
 	if len(mutations) == 0 {
 		return &MutatingState{
-			state:     s,
-			mutations: NewMutations(),
+			epoch:       epoch,
+			state:       s,
+			mutations:   NewMutations(),
+			walletJoins: make(map[crypto.Token]int),
 		}
 	}
+	grouped := mutations[0]
 	if len(mutations) > 1 {
-		mutations[0].Merge(mutations[1:]...)
+		grouped = mutations[0].Merge(mutations[1:]...)
 	}
 	return &MutatingState{
-		state:     s,
-		mutations: mutations[0],
+		epoch:       epoch,
+		state:       s,
+		mutations:   grouped,
+		walletJoins: make(map[crypto.Token]int),
 	}
 }
 
-func (s *State) Incorporate(mutations *Mutations) {
+// SetValidationLogger sets the logger of validation results and the levels at
+// which accepted and rejected actions are logged.
+func (s *State) SetValidationLogger(logger *slog.Logger, accepted, rejected slog.Level) {
+	s.validationLog = validationLog{logger: logger, accepted: accepted, rejected: rejected}
+}
+
+// Config returns the consensus settings of the axé network of the state.
+func (s *State) Config() Config {
+	return s.config
+}
+
+// Codec returns the codec of the axé network of the state.
+func (s *State) Codec() *Codec {
+	return s.codec
+}
+
+// Incorporate applies the mutations validated for the given epoch to the
+// state.
+func (s *State) Incorporate(epoch uint64, mutations *Mutations) {
 	if mutations == nil {
 		return
 	}
-	for hash := range mutations.GrantPower {
+	s.markIncorporating(epoch)
+	for hash, grant := range mutations.GrantPower {
 		s.Attorneys.InsertHash(hash)
+		s.Grants.Grant(grant)
 	}
-	for hash := range mutations.RevokePower {
+	for hash, grant := range mutations.RevokePower {
 		s.Attorneys.RemoveHash(hash)
+		s.Grants.Revoke(grant.Author, grant.Attorney)
 	}
 	for hash := range mutations.NewMembers {
 		s.Members.InsertHash(hash)
 	}
+	for hash := range mutations.RemovedCaption {
+		s.Captions.RemoveHash(hash)
+		s.Handles.Remove(hash)
+	}
 	for hash := range mutations.NewCaption {
-		s.Captions.ExistsHash(hash)
+		s.Captions.InsertHash(hash)
+	}
+	for token, handle := range mutations.NewHandles {
+		hash := crypto.Hasher([]byte(handle))
+		// the handle might have been released again within the block
+		if mutations.HasCaption(hash) {
+			s.Handles.Set(hash, token[:])
+		}
+		s.Tokens.Set(crypto.HashToken(token), encodeHandle(handle))
+	}
+	for token, profile := range mutations.NewProfiles {
+		s.Profiles.Set(token, profile)
+	}
+	for token, policy := range mutations.NewThresholds {
+		if policy.Threshold == 0 {
+			s.Thresholds.Delete(token)
+		} else {
+			s.Thresholds.Set(token, policy)
+		}
+	}
+	for token, policy := range mutations.NewGuardians {
+		if policy.Threshold == 0 {
+			s.Guardians.Delete(token)
+		} else {
+			s.Guardians.Set(token, policy)
+		}
+	}
+	for token, cancelled := range mutations.CancelledRecoveries {
+		s.Recoveries.Delete(token)
+		s.Cancellations.Set(token, cancelled)
+	}
+	for token, recovery := range mutations.NewRecoveries {
+		s.Recoveries.Set(token, recovery)
+	}
+	for code, protocol := range mutations.NewProtocols {
+		s.Protocols.Set(code, protocol)
+	}
+	for hash, invitation := range mutations.NewInvitations {
+		s.Invitations.Set(hash, invitation)
+	}
+	for hash := range mutations.UsedInvitations {
+		s.Invitations.Delete(hash)
+	}
+	for old, token := range mutations.Rotations {
+		s.rotate(old, token, mutations)
+	}
+	for token := range mutations.Leaving {
+		s.leave(token)
+	}
+	s.unlockRecoveries(epoch)
+	s.pruneInvitations(epoch)
+	s.incorporateSubProtocols(epoch, mutations.SubProtocols)
+	s.Epoch = epoch
+	s.checkpoint()
+}
+
+// rotate moves the membership, handle, profile and granted powers of attorney
+// of old to token and retires old. Records the incorporated mutations already
+// set for token, such as a handle changed by token after the rotation within
+// the same block, are newer and not overwritten by those of old.
+func (s *State) rotate(old, token crypto.Token, mutations *Mutations) {
+	s.Members.RemoveHash(crypto.HashToken(old))
+	s.Members.InsertHash(crypto.HashToken(token))
+	s.Retired.InsertHash(crypto.HashToken(old))
+	if handle, ok := s.HandleOf(old); ok {
+		s.Tokens.Remove(crypto.HashToken(old))
+		if _, changed := mutations.NewHandles[token]; !changed {
+			s.Handles.Set(crypto.Hasher([]byte(handle)), token[:])
+			s.Tokens.Set(crypto.HashToken(token), encodeHandle(handle))
+		}
+	}
+	if profile, ok := s.Profiles.Get(old); ok {
+		s.Profiles.Delete(old)
+		if _, changed := mutations.NewProfiles[token]; !changed {
+			s.Profiles.Set(token, profile)
+		}
+	}
+	if policy, ok := s.Thresholds.Get(old); ok {
+		s.Thresholds.Delete(old)
+		if _, changed := mutations.NewThresholds[token]; !changed {
+			s.Thresholds.Set(token, policy)
+		}
+	}
+	if policy, ok := s.Guardians.Get(old); ok {
+		s.Guardians.Delete(old)
+		if _, changed := mutations.NewGuardians[token]; !changed {
+			s.Guardians.Set(token, policy)
+		}
+	}
+	s.Recoveries.Delete(old)
+	s.Cancellations.Delete(old)
+	for code, protocol := range s.Protocols.Items() {
+		if protocol.Owner.Equal(old) {
+			protocol.Owner = token
+			s.Protocols.Set(code, protocol)
+		}
+	}
+	for _, grant := range s.Grants.Granted(old) {
+		s.Attorneys.RemoveHash(grant.Hash())
+		s.Grants.Revoke(grant.Author, grant.Attorney)
+		grant.Author = token
+		hash := grant.Hash()
+		if mutations.HasGrantPower(hash) || mutations.HasRevokePower(hash) {
+			continue
+		}
+		s.Attorneys.InsertHash(hash)
+		s.Grants.Grant(grant)
+	}
+}
+
+// unlockRecoveries rotates the identities whose recovery unlocks at epoch. Recoveries
+// are applied in token order so that every node resolves conflicting
+// recoveries alike.
+func (s *State) unlockRecoveries(epoch uint64) {
+	unlocked := make([]crypto.Token, 0)
+	for token, recovery := range s.Recoveries.Items() {
+		if recovery.Unlock <= epoch {
+			unlocked = append(unlocked, token)
+		}
+	}
+	sort.Slice(unlocked, func(i, j int) bool {
+		return bytes.Compare(unlocked[i][:], unlocked[j][:]) < 0
+	})
+	for _, old := range unlocked {
+		recovery, _ := s.Recoveries.Get(old)
+		s.Recoveries.Delete(old)
+		// the new token might have joined or been retired meanwhile
+		if s.HasMember(old) && !s.HasMember(recovery.NewToken) && !s.IsRetired(recovery.NewToken) {
+			s.rotate(old, recovery.NewToken, NewMutations())
+		}
+	}
+}
+
+// pruneInvitations deletes the invitations expired at epoch, which can no
+// longer be used.
+func (s *State) pruneInvitations(epoch uint64) {
+	for hash, invitation := range s.Invitations.Items() {
+		if !invitation.ValidAt(epoch) {
+			s.Invitations.Delete(hash)
+		}
 	}
 }
 
+// leave removes token from the members with its profile and the powers of
+// attorney it granted. A released handle was already removed from the
+// captions.
+func (s *State) leave(token crypto.Token) {
+	s.Members.RemoveHash(crypto.HashToken(token))
+	if handle, ok := s.HandleOf(token); ok {
+		hash := crypto.Hasher([]byte(handle))
+		// the handle might have been claimed by someone else meanwhile
+		if owner, ok := s.TokenOf(handle); ok && owner.Equal(token) {
+			s.Handles.Remove(hash)
+		}
+		s.Tokens.Remove(crypto.HashToken(token))
+	}
+	s.Profiles.Delete(token)
+	s.Thresholds.Delete(token)
+	s.Guardians.Delete(token)
+	s.Recoveries.Delete(token)
+	for _, grant := range s.Grants.Granted(token) {
+		s.Attorneys.RemoveHash(grant.Hash())
+		s.Grants.Revoke(grant.Author, grant.Attorney)
+	}
+}
+
+// markIncorporating records on the data path that the vaults are about to
+// change for epoch. The mark is removed by the checkpoint of epoch, so a state
+// interrupted in between is not recovered from vaults ahead of its checkpoint.
+func (s *State) markIncorporating(epoch uint64) {
+	if s.dataPath == "" {
+		return
+	}
+	data := make([]byte, 0, 8)
+	util.PutUint64(epoch, &data)
+	if err := os.WriteFile(filepath.Join(s.dataPath, incorporatingFile), data, 0644); err != nil {
+		slog.Error("State.markIncorporating: could not write mark", "error", err)
+	}
+}
+
+// checkpoint persists the epoch, config and store commitments so that the state can
+// be recovered after a restart.
+func (s *State) checkpoint() {
+	if s.dataPath == "" {
+		return
+	}
+	c := checkpoint{
+		Epoch:         s.Epoch,
+		Config:        s.config,
+		Members:       s.Members.Checksum(),
+		Captions:      s.Captions.Checksum(),
+		Attorneys:     s.Attorneys.Checksum(),
+		Retired:       s.Retired.Checksum(),
+		Handles:       s.Handles.Checksum(),
+		Tokens:        s.Tokens.Checksum(),
+		Profiles:      s.Profiles.Checksum(),
+		Grants:        s.Grants.Checksum(),
+		Thresholds:    s.Thresholds.Checksum(),
+		Guardians:     s.Guardians.Checksum(),
+		Recoveries:    s.Recoveries.Checksum(),
+		Cancellations: s.Cancellations.Checksum(),
+		Protocols:     s.Protocols.Checksum(),
+		Invitations:   s.Invitations.Checksum(),
+		Checksum:      s.vaultChecksum(),
+	}
+	if err := writeCheckpoint(filepath.Join(s.dataPath, checkpointFile), &c); err != nil {
+		slog.Error("State.checkpoint: could not write checkpoint", "error", err)
+		return
+	}
+	if err := os.Remove(filepath.Join(s.dataPath, incorporatingFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("State.checkpoint: could not remove incorporating mark", "error", err)
+	}
+}
+
+// ChecksumPoint returns a commitment to the whole social state: every axé
+// store combined with the checksums of registered sub-protocols that
+// implement SubProtocolState, in protocol code order. Without such
+// sub-protocols it is the axé store commitment. Nodes that incorporated the
+// same mutations have the same checksum point.
 func (s *State) ChecksumPoint() crypto.Hash {
-	return crypto.ZeroHash
+	checksum := s.vaultChecksum()
+	codes := make([]uint32, 0, len(s.subProtocols))
+	for code, sub := range s.subProtocols {
+		if _, ok := sub.(SubProtocolState); ok {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return checksum
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	data := append(make([]byte, 0, (1+len(codes))*crypto.Size), checksum[:]...)
+	for _, code := range codes {
+		util.PutUint32(code, &data)
+		util.PutHash(s.subProtocols[code].(SubProtocolState).Checksum(), &data)
+	}
+	return crypto.Hasher(data)
 }
 
+// vaultChecksum returns a commitment to every axé store of the state, the
+// checksum of each store in a fixed order. It is the checksum kept in
+// checkpoints, as sub-protocols recover their own state.
+func (s *State) vaultChecksum() crypto.Hash {
+	checksums := []crypto.Hash{
+		s.Members.Checksum(),
+		s.Captions.Checksum(),
+		s.Attorneys.Checksum(),
+		s.Retired.Checksum(),
+		s.Handles.Checksum(),
+		s.Tokens.Checksum(),
+		s.Profiles.Checksum(),
+		s.Grants.Checksum(),
+		s.Thresholds.Checksum(),
+		s.Guardians.Checksum(),
+		s.Recoveries.Checksum(),
+		s.Cancellations.Checksum(),
+		s.Protocols.Checksum(),
+		s.Invitations.Checksum(),
+	}
+	data := make([]byte, 0, len(checksums)*crypto.Size)
+	for _, checksum := range checksums {
+		data = append(data, checksum[:]...)
+	}
+	return crypto.Hasher(data)
+}
+
+// Recover reopens the vaults persisted on the state data path and restores
+// the last incorporated epoch and checksum. It refuses to recover a state
+// whose files are missing or do not agree with the checkpoint: vault journals
+// must match the vault files, logged stores and journals must replay to the
+// commitments of the checkpoint, the handle, token, member and caption
+// indexes must agree with each other, and vaults must not have been changed
+// by an incorporation interrupted before its checkpoint.
 func (s *State) Recover() error {
+	if s.dataPath == "" {
+		return errors.New("cannot recover axe state without data path")
+	}
+	for _, name := range stateFiles {
+		if _, err := os.Stat(filepath.Join(s.dataPath, name)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInconsistentState, err)
+		}
+	}
+	c, err := readCheckpoint(filepath.Join(s.dataPath, checkpointFile))
+	if err != nil {
+		return fmt.Errorf("could not read axe checkpoint: %w", err)
+	}
+	if c.Config != s.config {
+		return fmt.Errorf("%w: config does not match checkpoint", ErrInconsistentState)
+	}
+	if data, err := os.ReadFile(filepath.Join(s.dataPath, incorporatingFile)); err == nil {
+		epoch, _ := util.ParseUint64(data, 0)
+		return fmt.Errorf("%w: incorporation of epoch %d interrupted after checkpoint of epoch %d", ErrInconsistentState, epoch, c.Epoch)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrInconsistentState, err)
+	}
+	var indexes stateIndexes
+	s.Members = OpenHashVault("members", 8, s.dataPath, indexes.member)
+	s.Captions = OpenHashVault("captions", 8, s.dataPath, indexes.caption)
+	s.Attorneys = OpenHashVault("poa", 8, s.dataPath, nil)
+	s.Retired = OpenHashVault("retired", 8, s.dataPath, nil)
+	s.Handles = OpenIndexVault("handles", 8, crypto.TokenSize, s.dataPath, indexes.handle)
+	s.Tokens = OpenIndexVault("tokens", 8, 1+MaxHandleSize, s.dataPath, indexes.token)
+	s.Profiles = openKeyedStore("profiles", s.dataPath, profileCodec)
+	s.Grants = OpenGrantStore("grants", s.dataPath)
+	s.Thresholds = openKeyedStore("thresholds", s.dataPath, thresholdCodec)
+	s.Guardians = openKeyedStore("guardians", s.dataPath, thresholdCodec)
+	s.Recoveries = openKeyedStore("recoveries", s.dataPath, recoveryCodec)
+	s.Cancellations = openKeyedStore("cancellations", s.dataPath, cancellationCodec)
+	s.Protocols = openKeyedStore("protocols", s.dataPath, protocolCodec)
+	s.Invitations = openKeyedStore("invitations", s.dataPath, invitationCodec)
+	if !s.opened() {
+		s.Shutdown()
+		return fmt.Errorf("%w: could not open axe state vaults", ErrInconsistentState)
+	}
+	if err := indexes.check(); err != nil {
+		s.Shutdown()
+		return fmt.Errorf("%w: %v", ErrInconsistentState, err)
+	}
+	replayed := []struct {
+		name     string
+		checksum crypto.Hash
+		want     crypto.Hash
+	}{
+		{"members", s.Members.Checksum(), c.Members},
+		{"captions", s.Captions.Checksum(), c.Captions},
+		{"poa", s.Attorneys.Checksum(), c.Attorneys},
+		{"retired", s.Retired.Checksum(), c.Retired},
+		{"handles", s.Handles.Checksum(), c.Handles},
+		{"tokens", s.Tokens.Checksum(), c.Tokens},
+		{"profiles", s.Profiles.Checksum(), c.Profiles},
+		{"grants", s.Grants.Checksum(), c.Grants},
+		{"thresholds", s.Thresholds.Checksum(), c.Thresholds},
+		{"guardians", s.Guardians.Checksum(), c.Guardians},
+		{"recoveries", s.Recoveries.Checksum(), c.Recoveries},
+		{"cancellations", s.Cancellations.Checksum(), c.Cancellations},
+		{"protocols", s.Protocols.Checksum(), c.Protocols},
+		{"invitations", s.Invitations.Checksum(), c.Invitations},
+	}
+	for _, store := range replayed {
+		if store.checksum != store.want {
+			s.Shutdown()
+			return fmt.Errorf("%w: %s log does not match checkpoint of epoch %d", ErrInconsistentState, store.name, c.Epoch)
+		}
+	}
+	if s.vaultChecksum() != c.Checksum {
+		s.Shutdown()
+		return fmt.Errorf("%w: checksum does not match checkpoint", ErrInconsistentState)
+	}
+	s.Epoch = c.Epoch
+	return nil
+}
+
+// stateIndexes collects the member, caption, handle and token vaults while
+// their journals are replayed, to check that they agree with each other.
+type stateIndexes struct {
+	members  map[crypto.Hash]struct{}
+	captions map[crypto.Hash]struct{}
+	handles  map[crypto.Hash]crypto.Token
+	tokens   map[crypto.Hash]string
+}
+
+func (i *stateIndexes) member(hash crypto.Hash) {
+	if i.members == nil {
+		i.members = make(map[crypto.Hash]struct{})
+	}
+	i.members[hash] = struct{}{}
+}
+
+func (i *stateIndexes) caption(hash crypto.Hash) {
+	if i.captions == nil {
+		i.captions = make(map[crypto.Hash]struct{})
+	}
+	i.captions[hash] = struct{}{}
+}
+
+func (i *stateIndexes) handle(hash crypto.Hash, value []byte) {
+	if i.handles == nil {
+		i.handles = make(map[crypto.Hash]crypto.Token)
+	}
+	var token crypto.Token
+	copy(token[:], value)
+	i.handles[hash] = token
+}
+
+// token records a token whose handle cannot be decoded with an empty handle,
+// which no handle index entry can match.
+func (i *stateIndexes) token(hash crypto.Hash, value []byte) {
+	if i.tokens == nil {
+		i.tokens = make(map[crypto.Hash]string)
+	}
+	handle, _ := decodeHandle(value)
+	i.tokens[hash] = handle
+}
+
+// check checks that every member has a handle, that the handle and token
+// indexes are the inverse of each other, and that indexed handles are
+// captions.
+func (i *stateIndexes) check() error {
+	for hash := range i.members {
+		if _, ok := i.tokens[hash]; !ok {
+			return errors.New("member without handle")
+		}
+	}
+	for hash, handle := range i.tokens {
+		if _, ok := i.members[hash]; !ok {
+			return errors.New("handle of a token that is not a member")
+		}
+		token, ok := i.handles[crypto.Hasher([]byte(handle))]
+		if !ok || crypto.HashToken(token) != hash {
+			return errors.New("token index does not match handle index")
+		}
+	}
+	for hash, token := range i.handles {
+		if handle, ok := i.tokens[crypto.HashToken(token)]; !ok || crypto.Hasher([]byte(handle)) != hash {
+			return errors.New("handle index does not match token index")
+		}
+		if _, ok := i.captions[hash]; !ok {
+			return errors.New("indexed handle is not a caption")
+		}
+	}
 	return nil
 }
 
+// PowerOfAttorney checks if attorney has unrestricted power to sign on behalf
+// of token at the epoch of the last incorporated block.
 func (s *State) PowerOfAttorney(token, attorney crypto.Token) bool {
+	if s.IsRetired(attorney) {
+		return false
+	}
 	if token.Equal(attorney) {
 		return true
 	}
-	join := append(token[:], attorney[:]...)
-	hash := crypto.Hasher(join)
-	return s.Attorneys.ExistsHash(hash)
+	grant, ok := s.validGrant(token, attorney, 0)
+	return ok && grant.Scope == nil
+}
+
+// validGrant returns the grant of token to attorney if it and every grant up
+// its delegation chain are valid at the epoch of the last incorporated block.
+func (s *State) validGrant(token, attorney crypto.Token, depth int) (AttorneyGrant, bool) {
+	if depth > MaxDelegationDepth || s.IsRetired(attorney) {
+		return AttorneyGrant{}, false
+	}
+	if !s.Attorneys.ExistsHash(attorneyHash(token, attorney)) {
+		return AttorneyGrant{}, false
+	}
+	grant, ok := s.Grants.Get(token, attorney)
+	if !ok || !grant.ValidAt(s.Epoch) {
+		return AttorneyGrant{}, false
+	}
+	if grant.Delegated() {
+		parent, ok := s.validGrant(token, grant.Via, depth+1)
+		if !ok || parent.Depth <= grant.Depth {
+			return AttorneyGrant{}, false
+		}
+	}
+	return grant, true
+}
+
+// GrantOf returns the power of attorney granted by token to attorney, if any,
+// including expired grants.
+func (s *State) GrantOf(token, attorney crypto.Token) (AttorneyGrant, bool) {
+	return s.Grants.Get(token, attorney)
+}
+
+// ThresholdOf returns the threshold policy of the member with the given
+// token.
+func (s *State) ThresholdOf(token crypto.Token) (ThresholdPolicy, bool) {
+	return s.Thresholds.Get(token)
+}
+
+// GuardiansOf returns the guardians of the member with the given token.
+func (s *State) GuardiansOf(token crypto.Token) (ThresholdPolicy, bool) {
+	return s.Guardians.Get(token)
+}
+
+// RecoveryOf returns the pending recovery of the member with the given token.
+func (s *State) RecoveryOf(token crypto.Token) (Recovery, bool) {
+	return s.Recoveries.Get(token)
+}
+
+// RecoveryCancelledAt returns the epoch at which token last cancelled a
+// recovery of its identity.
+func (s *State) RecoveryCancelledAt(token crypto.Token) (uint64, bool) {
+	return s.Cancellations.Get(token)
+}
+
+// ProtocolOf returns the registration of the protocol code.
+func (s *State) ProtocolOf(protocol uint32) (RegisteredProtocol, bool) {
+	return s.Protocols.Get(protocol)
+}
+
+// InvitationOf returns the unused invitation with the given hash.
+func (s *State) InvitationOf(hash crypto.Hash) (Invitation, bool) {
+	return s.Invitations.Get(hash)
+}
+
+// IsRetired checks if token was replaced by a key rotation. Retired tokens can
+// neither join again nor act as attorneys.
+func (s *State) IsRetired(token crypto.Token) bool {
+	return s.Retired.ExistsHash(crypto.HashToken(token))
 }
 
 func (s *State) HasMember(token crypto.Token) bool {
@@ -80,8 +707,73 @@ func (s *State) HasHandle(handle string) bool {
 	return s.Captions.ExistsHash(hash)
 }
 
+// TokenOf returns the token of the member owning handle.
+func (s *State) TokenOf(handle string) (crypto.Token, bool) {
+	var token crypto.Token
+	data, ok := s.Handles.Get(crypto.Hasher([]byte(handle)))
+	if !ok {
+		return token, false
+	}
+	copy(token[:], data)
+	return token, true
+}
+
+// HandleOf returns the handle of the member with the given token.
+func (s *State) HandleOf(token crypto.Token) (string, bool) {
+	data, ok := s.Tokens.Get(crypto.HashToken(token))
+	if !ok {
+		return "", false
+	}
+	return decodeHandle(data)
+}
+
+// Profile returns the latest details of the member with the given token and
+// the epoch they were set.
+func (s *State) Profile(token crypto.Token) (Profile, bool) {
+	return s.Profiles.Get(token)
+}
+
 func (s *State) Shutdown() {
-	s.Members.Close()
-	s.Attorneys.Close()
-	s.Captions.Close()
+	if s.Members != nil {
+		s.Members.Close()
+	}
+	if s.Attorneys != nil {
+		s.Attorneys.Close()
+	}
+	if s.Retired != nil {
+		s.Retired.Close()
+	}
+	if s.Captions != nil {
+		s.Captions.Close()
+	}
+	if s.Handles != nil {
+		s.Handles.Close()
+	}
+	if s.Tokens != nil {
+		s.Tokens.Close()
+	}
+	if s.Profiles != nil {
+		s.Profiles.Close()
+	}
+	if s.Grants != nil {
+		s.Grants.Close()
+	}
+	if s.Thresholds != nil {
+		s.Thresholds.Close()
+	}
+	if s.Guardians != nil {
+		s.Guardians.Close()
+	}
+	if s.Recoveries != nil {
+		s.Recoveries.Close()
+	}
+	if s.Cancellations != nil {
+		s.Cancellations.Close()
+	}
+	if s.Protocols != nil {
+		s.Protocols.Close()
+	}
+	if s.Invitations != nil {
+		s.Invitations.Close()
+	}
 }
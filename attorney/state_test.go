@@ -0,0 +1,199 @@
+package attorney
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// testMember is a key pair of a test identity.
+type testMember struct {
+	token crypto.Token
+	key   crypto.PrivateKey
+}
+
+func newMember() testMember {
+	token, key := newKey()
+	return testMember{token: token, key: key}
+}
+
+// newTestState creates a genesis state on a temporary data path.
+func newTestState(t *testing.T, config Config) *State {
+	t.Helper()
+	state := NewGenesisState(t.TempDir(), config)
+	if state == nil {
+		t.Fatal("could not create genesis state")
+	}
+	t.Cleanup(state.Shutdown)
+	return state
+}
+
+// check validates action, paid by wallet, against the mutating state.
+func check(v *MutatingState, wallet crypto.PrivateKey, action Action) Result {
+	envelope := &Envelope{Action: action, Fee: 1}
+	v.state.codec.SignEnvelope(envelope, wallet)
+	return v.Check(v.state.codec.SerializeEnvelope(envelope))
+}
+
+// mustAccept validates actions in a new block at epoch, paid by the author
+// wallet, and incorporates the block. Every action must be accepted.
+func mustAccept(t *testing.T, state *State, epoch uint64, wallet crypto.PrivateKey, actions ...Action) {
+	t.Helper()
+	v := state.Validator(epoch)
+	for _, action := range actions {
+		if result := check(v, wallet, action); !result.Accepted {
+			t.Fatalf("epoch %d: kind %d rejected: %v", epoch, action.Kind(), result.Reason)
+		}
+	}
+	state.Incorporate(epoch, v.Mutations())
+}
+
+// signedJoin returns a signed join of member with handle.
+func signedJoin(epoch uint64, member testMember, handle string) *JoinNetwork {
+	action := &JoinNetwork{Epoch: epoch, Version: CurrentVersion, Author: member.token, Handle: handle, Details: "{}"}
+	action.Sign(member.key)
+	return action
+}
+
+func signedGrant(epoch uint64, author testMember, attorney crypto.Token) *GrantPowerOfAttorney {
+	action := &GrantPowerOfAttorney{Epoch: epoch, Version: CurrentVersion, Author: author.token, Attorney: attorney}
+	action.Sign(author.key)
+	return action
+}
+
+func TestChecksumIndependentOfGrantOrder(t *testing.T) {
+	author, first, second := newMember(), newMember(), newMember()
+	states := []*State{newTestState(t, DefaultConfig()), newTestState(t, DefaultConfig())}
+	for _, state := range states {
+		mustAccept(t, state, 1, author.key, signedJoin(1, author, "author"))
+	}
+	mustAccept(t, states[0], 2, author.key, signedGrant(2, author, first.token))
+	mustAccept(t, states[0], 3, author.key, signedGrant(3, author, second.token))
+	mustAccept(t, states[1], 2, author.key, signedGrant(2, author, second.token))
+	mustAccept(t, states[1], 3, author.key, signedGrant(3, author, first.token))
+	if states[0].ChecksumPoint() != states[1].ChecksumPoint() {
+		t.Error("grants incorporated in a different order changed the checksum")
+	}
+	if len(states[0].Grants.Granted(author.token)) != 2 {
+		t.Error("grants were not recorded")
+	}
+}
+
+// persistedState incorporates a few blocks in a state on a temporary data path
+// and shuts it down. It returns the data path and the checksum of the state.
+func persistedState(t *testing.T, config Config) (string, crypto.Hash) {
+	t.Helper()
+	dataPath := t.TempDir()
+	state := NewGenesisState(dataPath, config)
+	if state == nil {
+		t.Fatal("could not create genesis state")
+	}
+	defer state.Shutdown()
+	first, second, third := newMember(), newMember(), newMember()
+	mustAccept(t, state, 1, first.key, signedJoin(1, first, "first"), signedJoin(1, second, "second"), signedJoin(1, third, "third"))
+	change := &ChangeHandle{Epoch: 2, Version: CurrentVersion, Author: first.token, Handle: "renamed", Signer: first.token}
+	change.Sign(first.key)
+	mustAccept(t, state, 2, first.key, change, signedGrant(2, first, second.token), signedLeave(2, third))
+	mustAccept(t, state, 3, second.key, signedRotation(3, second, newMember()))
+	return dataPath, state.ChecksumPoint()
+}
+
+func TestRecoverState(t *testing.T) {
+	dataPath, checksum := persistedState(t, DefaultConfig())
+	state, err := RecoverState(dataPath, DefaultConfig())
+	if err != nil {
+		t.Fatalf("could not recover state: %v", err)
+	}
+	defer state.Shutdown()
+	if state.Epoch != 3 || state.ChecksumPoint() != checksum {
+		t.Errorf("recovered epoch %d with a different checksum", state.Epoch)
+	}
+	if token, ok := state.TokenOf("renamed"); !ok || !state.HasMember(token) {
+		t.Error("recovered state lost a handle")
+	}
+}
+
+func TestRecoverRefusesInconsistentState(t *testing.T) {
+	tampers := map[string]func(t *testing.T, dataPath string){
+		"config": func(t *testing.T, dataPath string) {},
+		"interrupted": func(t *testing.T, dataPath string) {
+			writeFile(t, filepath.Join(dataPath, incorporatingFile), []byte{4, 0, 0, 0, 0, 0, 0, 0})
+		},
+		"journal": func(t *testing.T, dataPath string) {
+			// a member recorded by the journal but missing from the vault
+			journal := openAppendLog(journalName("members"), dataPath, func([]byte) {})
+			hash := crypto.HashToken(newMember().token)
+			journal.Append(append([]byte{insert}, hash[:]...))
+			journal.Close()
+		},
+		"indexes": func(t *testing.T, dataPath string) {
+			// a handle index out of step with the token index, committed
+			// in the checkpoint
+			state, err := RecoverState(dataPath, DefaultConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			state.Handles.Remove(crypto.Hasher([]byte("renamed")))
+			state.checkpoint()
+			state.Shutdown()
+		},
+		"log": func(t *testing.T, dataPath string) {
+			os.Remove(filepath.Join(dataPath, "grants"))
+			writeFile(t, filepath.Join(dataPath, "grants"), nil)
+		},
+	}
+	for name, tamper := range tampers {
+		dataPath, _ := persistedState(t, DefaultConfig())
+		tamper(t, dataPath)
+		config := DefaultConfig()
+		if name == "config" {
+			config.HandlePolicy = FreeHandle
+		}
+		state, err := RecoverState(dataPath, config)
+		if err == nil {
+			state.Shutdown()
+			t.Errorf("%s: recovered an inconsistent state", name)
+		} else if !errors.Is(err, ErrInconsistentState) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateKeyMovesIdentity(t *testing.T) {
+	old, attorney, member := newMember(), newMember(), newMember()
+	state := newTestState(t, DefaultConfig())
+	mustAccept(t, state, 1, old.key, signedJoin(1, old, "old"), signedJoin(1, attorney, "attorney"))
+	mustAccept(t, state, 2, old.key, signedGrant(2, old, attorney.token))
+	mustAccept(t, state, 3, old.key, signedRotation(3, old, member))
+	if state.HasMember(old.token) || !state.IsRetired(old.token) {
+		t.Error("rotated token was not retired")
+	}
+	if handle, ok := state.HandleOf(member.token); !ok || handle != "old" {
+		t.Error("handle was not moved to the new token")
+	}
+	if token, ok := state.TokenOf("old"); !ok || !token.Equal(member.token) {
+		t.Error("handle still points to the rotated token")
+	}
+	if _, ok := state.Profile(member.token); !ok {
+		t.Error("profile was not moved to the new token")
+	}
+	if !state.PowerOfAttorney(member.token, attorney.token) || state.PowerOfAttorney(old.token, attorney.token) {
+		t.Error("grants were not moved to the new token")
+	}
+	if result := check(state.Validator(4), old.key, signedGrant(4, old, newMember().token)); result.Reason != NotMember {
+		t.Errorf("rotated token still acts: %v", result.Reason)
+	}
+	if result := check(state.Validator(4), old.key, signedJoin(4, old, "again")); result.Accepted {
+		t.Error("rotated token joined again")
+	}
+}
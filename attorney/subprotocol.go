@@ -0,0 +1,78 @@
+package attorney
+
+import (
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// SubProtocol is a social protocol carried by axé voids under its own
+// protocol code. Once registered with a State its voids are validated by the
+// axé node itself, after axé has authenticated their author, and its state
+// changes are incorporated together with the axé ones.
+type SubProtocol interface {
+	// NewMutations returns empty pending changes of the protocol.
+	NewMutations() SubMutations
+	// Validate checks the data of a void authored by author in the block at
+	// epoch against the protocol state and the pending mutations, and
+	// records its changes in mutations only if it is accepted.
+	Validate(epoch uint64, author crypto.Token, data []byte, mutations SubMutations) bool
+	// Incorporate applies the mutations validated for epoch to the protocol
+	// state.
+	Incorporate(epoch uint64, mutations SubMutations)
+}
+
+// SubProtocolState is implemented by sub-protocols that keep state, so that
+// State.ChecksumPoint commits to it together with the axé state.
+type SubProtocolState interface {
+	// Checksum returns a commitment to the state of the protocol at the last
+	// incorporated epoch.
+	Checksum() crypto.Hash
+}
+
+// SubMutations are the pending changes of a sub-protocol.
+type SubMutations interface {
+	// Merge groups the receiver and others, in that order, into new
+	// mutations. Merge with no others returns a copy of the receiver.
+	Merge(others ...SubMutations) SubMutations
+}
+
+// RegisterSubProtocol plugs sub in the validation of voids with the given
+// protocol code, replacing any previously registered sub-protocol. Every node
+// of an axé network must register the same sub-protocols.
+func (s *State) RegisterSubProtocol(protocol uint32, sub SubProtocol) {
+	if s.subProtocols == nil {
+		s.subProtocols = make(map[uint32]SubProtocol)
+	}
+	s.subProtocols[protocol] = sub
+}
+
+// incorporateSubProtocols applies the mutations of every registered
+// sub-protocol in protocol code order.
+func (s *State) incorporateSubProtocols(epoch uint64, mutations map[uint32]SubMutations) {
+	codes := make([]uint32, 0, len(mutations))
+	for code := range mutations {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	for _, code := range codes {
+		if sub, ok := s.subProtocols[code]; ok {
+			sub.Incorporate(epoch, mutations[code])
+		}
+	}
+}
+
+// ValidateSubProtocol checks data of a void of protocol authored by author
+// with the registered sub-protocol, if any.
+func (s *MutatingState) ValidateSubProtocol(protocol uint32, author crypto.Token, data []byte) bool {
+	sub, ok := s.state.subProtocols[protocol]
+	if !ok {
+		return true
+	}
+	mutations, ok := s.mutations.SubProtocols[protocol]
+	if !ok {
+		mutations = sub.NewMutations()
+		s.mutations.SubProtocols[protocol] = mutations
+	}
+	return sub.Validate(s.epoch, author, data, mutations)
+}
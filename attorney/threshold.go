@@ -0,0 +1,325 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// ThresholdPolicy requires Void actions of a member to be signed by at least
+// Threshold distinct tokens among Signers.
+type ThresholdPolicy struct {
+	Threshold byte
+	Signers   []crypto.Token
+}
+
+// Allows checks if token is one of the signers of the policy.
+func (t ThresholdPolicy) Allows(token crypto.Token) bool {
+	for _, signer := range t.Signers {
+		if signer.Equal(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid checks that the threshold can be met and the signers are distinct.
+func (t ThresholdPolicy) Valid() bool {
+	if int(t.Threshold) > len(t.Signers) || len(t.Signers) > 255 {
+		return false
+	}
+	unique := make(map[crypto.Token]struct{})
+	for _, signer := range t.Signers {
+		unique[signer] = struct{}{}
+	}
+	return len(unique) == len(t.Signers)
+}
+
+// Met checks if at least Threshold distinct signers of the policy are among
+// signers. Signers retired by a key rotation or no longer members of the
+// network according to v are not counted.
+func (t ThresholdPolicy) Met(v ActionValidator, signers []crypto.Token) bool {
+	signed := make(map[crypto.Token]struct{})
+	for _, signer := range signers {
+		if t.Allows(signer) && !v.IsRetired(signer) && v.HasMember(signer) {
+			signed[signer] = struct{}{}
+		}
+	}
+	return len(signed) >= int(t.Threshold)
+}
+
+func putTokens(tokens []crypto.Token, data *[]byte) {
+	util.PutByte(byte(len(tokens)), data)
+	for _, token := range tokens {
+		util.PutToken(token, data)
+	}
+}
+
+func parseTokens(data []byte, position int) ([]crypto.Token, int) {
+	if position >= len(data) {
+		return nil, len(data) + 1
+	}
+	count := int(data[position])
+	position = position + 1
+	tokens := make([]crypto.Token, count)
+	for n := 0; n < count; n++ {
+		tokens[n], position = util.ParseToken(data, position)
+	}
+	return tokens, position
+}
+
+func putThresholdPolicy(policy ThresholdPolicy, data *[]byte) {
+	util.PutByte(policy.Threshold, data)
+	putTokens(policy.Signers, data)
+}
+
+func parseThresholdPolicy(data []byte, position int) (ThresholdPolicy, int) {
+	policy := ThresholdPolicy{}
+	if position >= len(data) {
+		return policy, len(data) + 1
+	}
+	policy.Threshold = data[position]
+	policy.Signers, position = parseTokens(data, position+1)
+	return policy, position
+}
+
+var thresholdCodec = keyedCodec[crypto.Token, ThresholdPolicy]{
+	putKey:     util.PutToken,
+	parseKey:   util.ParseToken,
+	putValue:   putThresholdPolicy,
+	parseValue: parseThresholdPolicy,
+}
+
+// SetThreshold sets the threshold policy of Author. A zero Threshold removes
+// the policy. Once a policy is set, the Void actions of Author must be
+// MultiVoid actions satisfying it, and changing or removing the policy must
+// be cosigned to meet it in a Cosigned action.
+type SetThreshold struct {
+	Epoch     uint64
+	Version   byte
+	Author    crypto.Token
+	Threshold byte
+	Signers   []crypto.Token
+	Signature crypto.Signature
+}
+
+func (t *SetThreshold) Tokens() []crypto.Token {
+	return append([]crypto.Token{t.Author}, t.Signers...)
+}
+
+func (t *SetThreshold) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(t.Author)
+	if !v.HasMember(t.Author) {
+		return reject(SetThresholdType, NotMember, memberHash)
+	}
+	if !thresholdApproved(v, t.Author) {
+		return reject(SetThresholdType, ThresholdNotMet, memberHash)
+	}
+	policy := ThresholdPolicy{Threshold: t.Threshold, Signers: t.Signers}
+	if !policy.Valid() {
+		return reject(SetThresholdType, InvalidThreshold, memberHash)
+	}
+	v.SetThreshold(t.Author, policy)
+	return accept(SetThresholdType, memberHash)
+}
+
+func (t *SetThreshold) Kind() byte {
+	return SetThresholdType
+}
+
+func (t *SetThreshold) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, t.Epoch, SetThresholdType, t.Version)
+	util.PutToken(t.Author, &bytes)
+	putThresholdPolicy(ThresholdPolicy{Threshold: t.Threshold, Signers: t.Signers}, &bytes)
+	return bytes
+}
+
+func (t *SetThreshold) Serialize() []byte {
+	return t.serialize(AxeProtocolCode)
+}
+
+func (t *SetThreshold) serialize(protocol [4]byte) []byte {
+	bytes := t.serializeToSign(protocol)
+	util.PutSignature(t.Signature, &bytes)
+	return bytes
+}
+
+func (t *SetThreshold) Sign(pk crypto.PrivateKey) {
+	t.sign(AxeProtocolCode, pk)
+}
+
+func (t *SetThreshold) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := t.serializeToSign(protocol)
+	t.Signature = pk.Sign(bytes)
+}
+
+func ParseSetThreshold(data []byte) *SetThreshold {
+	threshold, err := DecodeSetThreshold(data)
+	if err != nil {
+		return nil
+	}
+	return threshold
+}
+
+// DecodeSetThreshold is like ParseSetThreshold but returns the reason the
+// action could not be parsed.
+func DecodeSetThreshold(data []byte) (*SetThreshold, error) {
+	return decodeSetThreshold(data, AxeProtocolCode)
+}
+
+func decodeSetThreshold(data []byte, protocol [4]byte) (*SetThreshold, error) {
+	var err error
+	var policy ThresholdPolicy
+	threshold := SetThreshold{}
+	position := 0
+	if threshold.Epoch, threshold.Version, position, err = parseHeader(data, protocol, SetThresholdType); err != nil {
+		return nil, err
+	}
+	threshold.Author, position = util.ParseToken(data, position)
+	policy, position = parseThresholdPolicy(data, position)
+	threshold.Threshold, threshold.Signers = policy.Threshold, policy.Signers
+	if threshold.Signature, err = parseTail(data, position, threshold.Author); err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// MultiVoid is a Void action of a member with a threshold policy, signed by
+// several of the policy signers over the same payload. Unlike voids it
+// carries the code of the axé network, and the code of the protocol it
+// belongs to after its kind.
+type MultiVoid struct {
+	Epoch      uint64
+	Version    byte
+	Protocol   uint32
+	Author     crypto.Token
+	Data       []byte
+	Signers    []crypto.Token
+	Signatures []crypto.Signature
+}
+
+func (m *MultiVoid) Tokens() []crypto.Token {
+	return append([]crypto.Token{m.Author}, m.Signers...)
+}
+
+func (m *MultiVoid) Validate(v ActionValidator) Result {
+	memberHash := crypto.HashToken(m.Author)
+	if m.Epoch > v.Epoch() {
+		return reject(MultiVoidType, FutureEpoch)
+	}
+	if !v.HasMember(m.Author) {
+		return reject(MultiVoidType, NotMember, memberHash)
+	}
+	if !v.AcceptsProtocol(m.Protocol) {
+		return reject(MultiVoidType, UnregisteredProtocol, memberHash)
+	}
+	policy, ok := v.ThresholdOf(m.Author)
+	if !ok {
+		return reject(MultiVoidType, InvalidThreshold, memberHash)
+	}
+	if !policy.Met(v, m.Signers) {
+		return reject(MultiVoidType, ThresholdNotMet, memberHash)
+	}
+	if !v.ValidateSubProtocol(m.Protocol, m.Author, m.Data) {
+		return reject(MultiVoidType, SubProtocolRejected, memberHash)
+	}
+	return accept(MultiVoidType, memberHash)
+}
+
+func (m *MultiVoid) Kind() byte {
+	return MultiVoidType
+}
+
+func (m *MultiVoid) serializeToSign(protocol [4]byte) []byte {
+	bytes := putHeader(protocol, m.Epoch, MultiVoidType, m.Version)
+	util.PutUint32(m.Protocol, &bytes)
+	util.PutToken(m.Author, &bytes)
+	util.PutByteArray(m.Data, &bytes)
+	return bytes
+}
+
+func (m *MultiVoid) Serialize() []byte {
+	return m.serialize(AxeProtocolCode)
+}
+
+func (m *MultiVoid) serialize(protocol [4]byte) []byte {
+	bytes := m.serializeToSign(protocol)
+	putCosigners(m.Signers, m.Signatures, &bytes)
+	return bytes
+}
+
+// Sign adds the signature of pk to the action.
+func (m *MultiVoid) Sign(pk crypto.PrivateKey) {
+	m.sign(AxeProtocolCode, pk)
+}
+
+func (m *MultiVoid) sign(protocol [4]byte, pk crypto.PrivateKey) {
+	bytes := m.serializeToSign(protocol)
+	m.Signers = append(m.Signers, pk.PublicKey())
+	m.Signatures = append(m.Signatures, pk.Sign(bytes))
+}
+
+func ParseMultiVoid(data []byte) *MultiVoid {
+	void, err := DecodeMultiVoid(data)
+	if err != nil {
+		return nil
+	}
+	return void
+}
+
+// DecodeMultiVoid is like ParseMultiVoid but returns the reason the action
+// could not be parsed. Every signature must be valid.
+func DecodeMultiVoid(data []byte) (*MultiVoid, error) {
+	return decodeMultiVoid(data, AxeProtocolCode)
+}
+
+func decodeMultiVoid(data []byte, protocol [4]byte) (*MultiVoid, error) {
+	var err error
+	void := MultiVoid{}
+	position := 0
+	if void.Epoch, void.Version, position, err = parseHeader(data, protocol, MultiVoidType); err != nil {
+		return nil, err
+	}
+	void.Protocol, position = util.ParseUint32(data, position)
+	void.Author, position = util.ParseToken(data, position)
+	void.Data, position = util.ParseByteArray(data, position)
+	if void.Signers, void.Signatures, err = parseCosigners(data, position); err != nil {
+		return nil, err
+	}
+	return &void, nil
+}
+
+// putCosigners appends the count of signers followed by every signer and its
+// signature.
+func putCosigners(signers []crypto.Token, signatures []crypto.Signature, data *[]byte) {
+	util.PutByte(byte(len(signers)), data)
+	for n, signer := range signers {
+		util.PutToken(signer, data)
+		util.PutSignature(signatures[n], data)
+	}
+}
+
+// parseCosigners parses the trailing signers of an action starting at
+// position. Every signer must have signed data up to position.
+func parseCosigners(data []byte, position int) ([]crypto.Token, []crypto.Signature, error) {
+	if position >= len(data) {
+		return nil, nil, ErrTruncated
+	}
+	hashPosition := position
+	count := int(data[position])
+	position = position + 1
+	signers := make([]crypto.Token, count)
+	signatures := make([]crypto.Signature, count)
+	for n := 0; n < count; n++ {
+		signers[n], position = util.ParseToken(data, position)
+		signatures[n], position = util.ParseSignature(data, position)
+	}
+	if position > len(data) {
+		return nil, nil, ErrTruncated
+	}
+	for n, signer := range signers {
+		if !signer.Verify(data[0:hashPosition], signatures[n]) {
+			return nil, nil, ErrBadSignature
+		}
+	}
+	return signers, signatures, nil
+}
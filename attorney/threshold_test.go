@@ -0,0 +1,79 @@
+package attorney
+
+import (
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+func signedRotation(epoch uint64, old, member testMember) *RotateKey {
+	action := &RotateKey{Epoch: epoch, Version: CurrentVersion, Author: old.token, NewToken: member.token}
+	action.Sign(old.key)
+	action.CounterSign(member.key)
+	return action
+}
+
+func signedMultiVoid(epoch uint64, author testMember, signers ...testMember) *MultiVoid {
+	action := &MultiVoid{Epoch: epoch, Protocol: 42, Author: author.token, Data: []byte("payload")}
+	for _, signer := range signers {
+		action.Sign(signer.key)
+	}
+	return action
+}
+
+// thresholdState returns a state where author requires two of signers to
+// cosign its actions. Every signer is a member.
+func thresholdState(t *testing.T, author testMember, signers ...testMember) *State {
+	t.Helper()
+	state := newTestState(t, DefaultConfig())
+	actions := []Action{signedJoin(1, author, "author")}
+	tokens := make([]crypto.Token, 0, len(signers))
+	for n, signer := range signers {
+		actions = append(actions, signedJoin(1, signer, string(rune('a'+n))))
+		tokens = append(tokens, signer.token)
+	}
+	mustAccept(t, state, 1, author.key, actions...)
+	policy := &SetThreshold{Epoch: 2, Version: CurrentVersion, Author: author.token, Threshold: 2, Signers: tokens}
+	policy.Sign(author.key)
+	mustAccept(t, state, 2, author.key, policy)
+	return state
+}
+
+func TestThresholdIgnoresRetiredSigners(t *testing.T) {
+	author, first, second := newMember(), newMember(), newMember()
+	state := thresholdState(t, author, first, second)
+	if result := check(state.Validator(3), author.key, signedMultiVoid(3, author, first, second)); !result.Accepted {
+		t.Fatalf("multi void rejected: %v", result.Reason)
+	}
+	mustAccept(t, state, 3, first.key, signedRotation(3, first, newMember()))
+	if result := check(state.Validator(4), author.key, signedMultiVoid(4, author, first, second)); result.Reason != ThresholdNotMet {
+		t.Errorf("multi void cosigned by a retired token: %v", result.Reason)
+	}
+	leave := &LeaveNetwork{Epoch: 4, Version: CurrentVersion, Author: author.token}
+	leave.Sign(author.key)
+	cosigned := &Cosigned{Epoch: 4, Version: CurrentVersion, Author: author.token, Action: leave}
+	cosigned.Sign(first.key)
+	cosigned.Sign(second.key)
+	if result := check(state.Validator(4), author.key, cosigned); result.Reason != ThresholdNotMet {
+		t.Errorf("action cosigned by a retired token: %v", result.Reason)
+	}
+}
+
+func TestThresholdIgnoresFormerMembers(t *testing.T) {
+	author, first, second := newMember(), newMember(), newMember()
+	state := thresholdState(t, author, first, second)
+	leave := &LeaveNetwork{Epoch: 3, Version: CurrentVersion, Author: second.token}
+	leave.Sign(second.key)
+	// the pending leave already drops the signer within the block
+	v := state.Validator(3)
+	if result := check(v, second.key, leave); !result.Accepted {
+		t.Fatalf("leave rejected: %v", result.Reason)
+	}
+	if result := check(v, author.key, signedMultiVoid(3, author, first, second)); result.Reason != ThresholdNotMet {
+		t.Errorf("multi void cosigned by a leaving member: %v", result.Reason)
+	}
+	state.Incorporate(3, v.Mutations())
+	if result := check(state.Validator(4), author.key, signedMultiVoid(4, author, first, second)); result.Reason != ThresholdNotMet {
+		t.Errorf("multi void cosigned by a former member: %v", result.Reason)
+	}
+}
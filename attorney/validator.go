@@ -1,56 +1,400 @@
 package attorney
 
 import (
-	"fmt"
-
 	"github.com/freehandle/breeze/crypto"
 )
 
+var _ ActionValidator = &MutatingState{}
+
 type MutatingState struct {
+	epoch     uint64
 	state     *State
 	mutations *Mutations
+	// wallet is the paying wallet of the transaction being validated.
+	// joins and walletJoins count the members joining in the block, which
+	// the mutations cannot tell apart from those of earlier blocks they were
+	// merged with.
+	wallet      *crypto.Token
+	joins       int
+	walletJoins map[crypto.Token]int
+	// cosigned is the author whose threshold policy is met by the cosigners
+	// of the action being validated, if any.
+	cosigned *crypto.Token
 }
 
 func (m *MutatingState) Mutations() *Mutations {
 	return m.mutations
 }
 
-func (s *MutatingState) SetNewGrantPower(token, attorney crypto.Token) bool {
-	join := append(token[:], attorney[:]...)
-	hash := crypto.Hasher(join)
-	s.mutations.GrantPower[hash] = struct{}{}
+// Epoch is the epoch of the block being validated.
+func (m *MutatingState) Epoch() uint64 {
+	return m.epoch
+}
+
+func (s *MutatingState) SetNewGrantPower(grant AttorneyGrant) bool {
+	hash := grant.Hash()
+	s.mutations.GrantPower[hash] = grant
+	delete(s.mutations.RevokePower, hash)
 	return true
 }
 
+// SetNewRevokePower revokes the power of attorney granted by token to
+// attorney together with every grant delegated down from it.
 func (s *MutatingState) SetNewRevokePower(token, attorney crypto.Token) bool {
-	join := append(token[:], attorney[:]...)
-	hash := crypto.Hasher(join)
-	s.mutations.GrantPower[hash] = struct{}{}
+	grant := AttorneyGrant{Author: token, Attorney: attorney}
+	hash := grant.Hash()
+	if _, revoked := s.mutations.RevokePower[hash]; revoked {
+		return true
+	}
+	s.mutations.RevokePower[hash] = grant
+	delete(s.mutations.GrantPower, hash)
+	for _, child := range s.state.Grants.Granted(token) {
+		if child.Via.Equal(attorney) {
+			s.SetNewRevokePower(token, child.Attorney)
+		}
+	}
+	for _, child := range s.mutations.GrantPower {
+		if child.Author.Equal(token) && child.Via.Equal(attorney) {
+			s.SetNewRevokePower(token, child.Attorney)
+		}
+	}
 	return true
 }
 
 func (s *MutatingState) SetNewMember(token crypto.Token, handle string) bool {
-	if (!s.HasHandle(handle)) && (!s.state.HasMember(token)) {
+	if len(handle) > MaxHandleSize {
+		return false
+	}
+	if s.IsRetired(token) {
+		return false
+	}
+	if s.IsLeaving(token) {
+		return false
+	}
+	if (!s.HasHandle(handle)) && (!s.HasMember(token)) {
 		captionHash := crypto.Hasher([]byte(handle))
 		tokenHash := crypto.HashToken(token)
 		s.mutations.NewMembers[tokenHash] = struct{}{}
+		// the handle might have been released within the block
+		delete(s.mutations.RemovedCaption, captionHash)
 		s.mutations.NewCaption[captionHash] = struct{}{}
+		s.mutations.NewHandles[token] = handle
+		s.joins++
+		if s.wallet != nil {
+			s.walletJoins[*s.wallet]++
+		}
 		return true
 	}
 	return false
 }
 
+// SetNewHandle releases the current handle of token and claims handle for it.
+func (s *MutatingState) SetNewHandle(token crypto.Token, handle string) bool {
+	if len(handle) > MaxHandleSize || s.HasHandle(handle) {
+		return false
+	}
+	old, ok := s.HandleOf(token)
+	if !ok {
+		return false
+	}
+	oldHash := crypto.Hasher([]byte(old))
+	newHash := crypto.Hasher([]byte(handle))
+	delete(s.mutations.NewCaption, oldHash)
+	s.mutations.RemovedCaption[oldHash] = struct{}{}
+	delete(s.mutations.RemovedCaption, newHash)
+	s.mutations.NewCaption[newHash] = struct{}{}
+	s.mutations.NewHandles[token] = handle
+	return true
+}
+
+// SetRotation moves the membership, handle and profile of token to newToken
+// and retires token.
+func (s *MutatingState) SetRotation(token, newToken crypto.Token) bool {
+	if !s.HasMember(token) || s.HasMember(newToken) || s.IsRetired(newToken) {
+		return false
+	}
+	for _, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			// token only becomes a member when the block is incorporated
+			return false
+		}
+	}
+	s.mutations.Rotations[token] = newToken
+	return true
+}
+
+// IsRetired checks if token was retired by a key rotation, including pending
+// rotations.
+func (s *MutatingState) IsRetired(token crypto.Token) bool {
+	if _, ok := s.mutations.Rotations[token]; ok {
+		return true
+	}
+	return s.state.IsRetired(token)
+}
+
+// SetLeave removes token from the members. Its handle is released or kept
+// reserved according to the handle policy of the state, and the powers of
+// attorney it granted are dropped when incorporated. A handle claimed by
+// token within the block is never indexed to it.
+func (s *MutatingState) SetLeave(token crypto.Token) bool {
+	if !s.HasMember(token) {
+		return false
+	}
+	if s.state.config.HandlePolicy == FreeHandle {
+		if handle, ok := s.HandleOf(token); ok {
+			hash := crypto.Hasher([]byte(handle))
+			delete(s.mutations.NewCaption, hash)
+			s.mutations.RemovedCaption[hash] = struct{}{}
+		}
+	}
+	delete(s.mutations.NewHandles, token)
+	s.mutations.Leaving[token] = struct{}{}
+	return true
+}
+
+// SetThreshold records the threshold policy of token.
+func (s *MutatingState) SetThreshold(token crypto.Token, policy ThresholdPolicy) {
+	s.mutations.NewThresholds[token] = policy
+}
+
+// ThresholdOf returns the threshold policy of token, including pending
+// changes.
+func (s *MutatingState) ThresholdOf(token crypto.Token) (ThresholdPolicy, bool) {
+	if s.IsRetired(token) || s.IsLeaving(token) {
+		return ThresholdPolicy{}, false
+	}
+	if policy, ok := s.mutations.NewThresholds[token]; ok {
+		return policy, policy.Threshold > 0
+	}
+	for old, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			return s.ThresholdOf(old)
+		}
+	}
+	return s.state.ThresholdOf(token)
+}
+
+// SetGuardians records the guardians of token.
+func (s *MutatingState) SetGuardians(token crypto.Token, policy ThresholdPolicy) {
+	s.mutations.NewGuardians[token] = policy
+}
+
+// GuardiansOf returns the guardians of token, including pending changes.
+func (s *MutatingState) GuardiansOf(token crypto.Token) (ThresholdPolicy, bool) {
+	if s.IsRetired(token) || s.IsLeaving(token) {
+		return ThresholdPolicy{}, false
+	}
+	if policy, ok := s.mutations.NewGuardians[token]; ok {
+		return policy, policy.Threshold > 0
+	}
+	for old, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			return s.GuardiansOf(old)
+		}
+	}
+	return s.state.GuardiansOf(token)
+}
+
+// SetRecovery records a recovery of token to newToken unlocking after the
+// recovery delay of the state. Only one recovery per token may be pending.
+func (s *MutatingState) SetRecovery(token, newToken crypto.Token) bool {
+	if _, ok := s.RecoveryOf(token); ok {
+		return false
+	}
+	s.mutations.NewRecoveries[token] = Recovery{NewToken: newToken, Unlock: s.epoch + s.state.config.RecoveryDelay}
+	return true
+}
+
+// SetCancelRecovery cancels the pending recovery of token.
+func (s *MutatingState) SetCancelRecovery(token crypto.Token) bool {
+	if _, ok := s.RecoveryOf(token); !ok {
+		return false
+	}
+	delete(s.mutations.NewRecoveries, token)
+	s.mutations.CancelledRecoveries[token] = s.epoch
+	return true
+}
+
+// RecoveryOf returns the pending recovery of token, including pending
+// requests and cancellations.
+func (s *MutatingState) RecoveryOf(token crypto.Token) (Recovery, bool) {
+	if recovery, ok := s.mutations.NewRecoveries[token]; ok {
+		return recovery, true
+	}
+	if _, cancelled := s.mutations.CancelledRecoveries[token]; cancelled {
+		return Recovery{}, false
+	}
+	return s.state.RecoveryOf(token)
+}
+
+// RecoveryCancelledAt returns the epoch at which token last cancelled a
+// recovery of its identity, including pending cancellations.
+func (s *MutatingState) RecoveryCancelledAt(token crypto.Token) (uint64, bool) {
+	if cancelled, ok := s.mutations.CancelledRecoveries[token]; ok {
+		return cancelled, true
+	}
+	return s.state.RecoveryCancelledAt(token)
+}
+
+// SetProtocol registers protocol unless it is the code of the axé network or
+// is already registered.
+func (s *MutatingState) SetProtocol(protocol uint32, registered RegisteredProtocol) bool {
+	if protocolCode(protocol) == s.state.codec.Protocol() {
+		return false
+	}
+	if _, ok := s.ProtocolOf(protocol); ok {
+		return false
+	}
+	s.mutations.NewProtocols[protocol] = registered
+	return true
+}
+
+// ProtocolOf returns the registration of protocol, including pending ones.
+func (s *MutatingState) ProtocolOf(protocol uint32) (RegisteredProtocol, bool) {
+	if registered, ok := s.mutations.NewProtocols[protocol]; ok {
+		return registered, true
+	}
+	return s.state.ProtocolOf(protocol)
+}
+
+// AcceptsProtocol checks if voids may target protocol. Any protocol is
+// accepted unless the state requires registered protocols.
+func (s *MutatingState) AcceptsProtocol(protocol uint32) bool {
+	if !s.state.config.RequireRegistered {
+		return true
+	}
+	_, ok := s.ProtocolOf(protocol)
+	return ok
+}
+
+// AdmitJoin checks the join limits of the state against the members joining
+// in the block.
+func (s *MutatingState) AdmitJoin() Reason {
+	if limit := int(s.state.config.JoinsPerBlock); limit > 0 && s.joins >= limit {
+		return BlockJoinLimit
+	}
+	if limit := int(s.state.config.JoinsPerWallet); limit > 0 && s.wallet != nil && s.walletJoins[*s.wallet] >= limit {
+		return WalletJoinLimit
+	}
+	return Accepted
+}
+
+// RequiresInvitation checks if the state requires new members to join with an
+// invitation.
+func (s *MutatingState) RequiresInvitation() bool {
+	return s.state.config.RequireInvitation
+}
+
+// SetInvitation records invitation, replacing a previous one of the same
+// invitee by the same inviter.
+func (s *MutatingState) SetInvitation(invitation Invitation) {
+	hash := invitation.Hash()
+	delete(s.mutations.UsedInvitations, hash)
+	s.mutations.NewInvitations[hash] = invitation
+}
+
+// SetUsedInvitation consumes the invitation with the given hash.
+func (s *MutatingState) SetUsedInvitation(hash crypto.Hash) bool {
+	if _, ok := s.InvitationOf(hash); !ok {
+		return false
+	}
+	delete(s.mutations.NewInvitations, hash)
+	s.mutations.UsedInvitations[hash] = struct{}{}
+	return true
+}
+
+// InvitationOf returns the unused invitation with the given hash, including
+// pending invitations.
+func (s *MutatingState) InvitationOf(hash crypto.Hash) (Invitation, bool) {
+	if invitation, ok := s.mutations.NewInvitations[hash]; ok {
+		return invitation, true
+	}
+	if _, used := s.mutations.UsedInvitations[hash]; used {
+		return Invitation{}, false
+	}
+	return s.state.InvitationOf(hash)
+}
+
+// IsLeaving checks if token left the network within the pending mutations.
+func (s *MutatingState) IsLeaving(token crypto.Token) bool {
+	_, ok := s.mutations.Leaving[token]
+	return ok
+}
+
+// HasGrant checks if token granted power of attorney to attorney, even if the
+// grant has expired.
+func (s *MutatingState) HasGrant(token, attorney crypto.Token) bool {
+	hash := attorneyHash(token, attorney)
+	if _, revoked := s.mutations.RevokePower[hash]; revoked {
+		return false
+	}
+	if _, ok := s.mutations.GrantPower[hash]; ok {
+		return true
+	}
+	return s.state.Attorneys.ExistsHash(hash)
+}
+
+// GrantOf returns the power of attorney granted by token to attorney if it is
+// valid at the epoch being validated. Delegated grants are only valid if
+// every grant up their delegation chain is.
+func (s *MutatingState) GrantOf(token, attorney crypto.Token) (AttorneyGrant, bool) {
+	return s.grantOf(token, attorney, 0)
+}
+
+func (s *MutatingState) grantOf(token, attorney crypto.Token, depth int) (AttorneyGrant, bool) {
+	if depth > MaxDelegationDepth || s.IsRetired(attorney) {
+		return AttorneyGrant{}, false
+	}
+	hash := attorneyHash(token, attorney)
+	if _, revoked := s.mutations.RevokePower[hash]; revoked {
+		return AttorneyGrant{}, false
+	}
+	grant, ok := s.mutations.GrantPower[hash]
+	if !ok {
+		if !s.state.Attorneys.ExistsHash(hash) {
+			return AttorneyGrant{}, false
+		}
+		if grant, ok = s.state.Grants.Get(token, attorney); !ok {
+			return AttorneyGrant{}, false
+		}
+	}
+	if !grant.ValidAt(s.epoch) {
+		return AttorneyGrant{}, false
+	}
+	if grant.Delegated() {
+		parent, ok := s.grantOf(token, grant.Via, depth+1)
+		if !ok || parent.Depth <= grant.Depth {
+			return AttorneyGrant{}, false
+		}
+	}
+	return grant, true
+}
+
+// PowerOfAttorney checks if attorney has unrestricted power to sign on behalf
+// of token.
 func (s *MutatingState) PowerOfAttorney(token, attorney crypto.Token) bool {
+	if s.IsRetired(attorney) {
+		return false
+	}
 	if token.Equal(attorney) {
 		return true
 	}
-	join := append(token[:], attorney[:]...)
-	hash := crypto.Hasher(join)
-	_, ok := s.mutations.GrantPower[hash]
-	return ok || s.state.Attorneys.ExistsHash(hash)
+	grant, ok := s.GrantOf(token, attorney)
+	return ok && grant.Scope == nil
 }
 
 func (s *MutatingState) HasMember(token crypto.Token) bool {
+	if _, rotated := s.mutations.Rotations[token]; rotated {
+		return false
+	}
+	if s.IsLeaving(token) {
+		return false
+	}
+	for _, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			return true
+		}
+	}
 	hash := crypto.HashToken(token)
 	_, ok := s.mutations.NewMembers[hash]
 	return ok || s.state.Members.ExistsHash(hash)
@@ -58,69 +402,141 @@ func (s *MutatingState) HasMember(token crypto.Token) bool {
 
 func (s *MutatingState) HasHandle(handle string) bool {
 	hash := crypto.Hasher([]byte(handle))
-	_, ok := s.mutations.NewCaption[hash]
-	return ok || s.state.Captions.ExistsHash(hash)
+	if _, ok := s.mutations.NewCaption[hash]; ok {
+		return true
+	}
+	if _, removed := s.mutations.RemovedCaption[hash]; removed {
+		return false
+	}
+	return s.state.Captions.ExistsHash(hash)
 }
 
-func (v *MutatingState) Validate(data []byte) bool {
-	kind := Kind(data)
-	if kind == Invalid {
-		return false
-	}
-	var ok bool
-	switch kind {
-	case JoinNetworkType:
-		join := ParseJoinNetwork(data)
-		if join != nil {
-			ok = v.SetNewMember(join.Author, join.Handle)
-			fmt.Printf("axe node %v:%+v\n", ok, *join)
-		} else {
-			fmt.Printf("axe node: could not parse join\n %v\n", data)
-		}
-	case UpdateInfoType:
-		update := ParseUpdateInfo(data)
-		if update != nil {
-			ok = v.PowerOfAttorney(update.Author, update.Signer)
-			if ok {
-				ok = v.HasMember(update.Author)
-			}
-			fmt.Printf("axe node %v:%+v\n", ok, *update)
-		} else {
-			fmt.Printf("axe node %v: could not parse update\n", ok)
+// SetProfile records details as the profile of token at the epoch of the
+// block being validated, whatever epoch the action declares, so that a
+// profile can always be updated by a later block.
+func (s *MutatingState) SetProfile(token crypto.Token, details string) {
+	s.mutations.NewProfiles[token] = Profile{Epoch: s.epoch, Details: details}
+}
+
+// Profile returns the latest profile of token, including pending updates.
+func (s *MutatingState) Profile(token crypto.Token) (Profile, bool) {
+	if s.IsRetired(token) || s.IsLeaving(token) {
+		return Profile{}, false
+	}
+	if profile, ok := s.mutations.NewProfiles[token]; ok {
+		return profile, true
+	}
+	for old, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			return s.Profile(old)
 		}
-	case GrantPowerOfAttorneyType:
-		grant := ParseGrantPowerOfAttorney(data)
-		if grant != nil {
-			ok = v.HasMember(grant.Author)
-			if ok {
-				ok = v.SetNewGrantPower(grant.Author, grant.Attorney)
+	}
+	return s.state.Profile(token)
+}
+
+// TokenOf returns the token owning handle, including members joining within
+// the pending mutations.
+func (s *MutatingState) TokenOf(handle string) (crypto.Token, bool) {
+	for token, pending := range s.mutations.NewHandles {
+		if pending == handle {
+			if newToken, rotated := s.mutations.Rotations[token]; rotated {
+				token = newToken
 			}
-			fmt.Printf("axe node %v:%+v\n", ok, *grant)
-		} else {
-			fmt.Printf("axe node %v: could not parse grant\n", ok)
-		}
-	case RevokePowerOfAttorneyType:
-		revoke := ParseRevokePowerOfAttorney(data)
-		if revoke != nil {
-			ok = v.HasMember(revoke.Author)
-			if ok {
-				ok = v.SetNewRevokePower(revoke.Author, revoke.Attorney)
+			if s.IsLeaving(token) {
+				return crypto.Token{}, false
 			}
-			fmt.Printf("axe node revoke %v:%+v\n", ok, *revoke)
-		} else {
-			fmt.Printf("axe node %v: could not parse revoke\n", ok)
+			return token, true
 		}
-	case VoidType:
-		void := ParseVoid(data)
-		if void != nil {
-			ok = v.HasMember(void.Author)
-			if ok {
-				ok = v.PowerOfAttorney(void.Author, void.Signer)
-			}
-			fmt.Printf("axe node void %v:%+v\n", ok, *void)
-		} else {
-			fmt.Printf("axe node %v: could not parse void\n", ok)
+	}
+	if _, removed := s.mutations.RemovedCaption[crypto.Hasher([]byte(handle))]; removed {
+		return crypto.Token{}, false
+	}
+	token, ok := s.state.TokenOf(handle)
+	if !ok || s.IsLeaving(token) {
+		return crypto.Token{}, false
+	}
+	if newToken, rotated := s.mutations.Rotations[token]; rotated {
+		return newToken, true
+	}
+	return token, true
+}
+
+// HandleOf returns the handle of token, including members joining within the
+// pending mutations.
+func (s *MutatingState) HandleOf(token crypto.Token) (string, bool) {
+	if s.IsRetired(token) || s.IsLeaving(token) {
+		return "", false
+	}
+	if handle, ok := s.mutations.NewHandles[token]; ok {
+		return handle, true
+	}
+	for old, pending := range s.mutations.Rotations {
+		if pending.Equal(token) {
+			return s.HandleOf(old)
 		}
 	}
-	return ok
+	return s.state.HandleOf(token)
+}
+
+// Cosigned runs validate with the threshold policy of author met.
+func (s *MutatingState) Cosigned(author crypto.Token, validate func(ActionValidator) Result) Result {
+	previous := s.cosigned
+	s.cosigned = &author
+	result := validate(s)
+	s.cosigned = previous
+	return result
+}
+
+// IsCosigned checks if the threshold policy of author is met by the cosigners
+// of the action being validated.
+func (s *MutatingState) IsCosigned(author crypto.Token) bool {
+	return s.cosigned != nil && s.cosigned.Equal(author)
+}
+
+// Atomically validates against a copy of the pending mutations and keeps
+// them only if the result is accepted.
+func (s *MutatingState) Atomically(validate func(ActionValidator) Result) Result {
+	fork := &MutatingState{
+		epoch:       s.epoch,
+		state:       s.state,
+		mutations:   s.mutations.Merge(),
+		wallet:      s.wallet,
+		joins:       s.joins,
+		walletJoins: make(map[crypto.Token]int),
+		cosigned:    s.cosigned,
+	}
+	for wallet, joins := range s.walletJoins {
+		fork.walletJoins[wallet] = joins
+	}
+	result := validate(fork)
+	if result.Accepted {
+		*s.mutations = *fork.mutations
+		s.joins = fork.joins
+		s.walletJoins = fork.walletJoins
+	}
+	return result
+}
+
+// Validate checks the axé action carried by a complete breeze void
+// transaction against the mutating state and records its mutations if it is
+// accepted. Joins are counted against the limits of the paying wallet.
+func (v *MutatingState) Validate(data []byte) bool {
+	return v.Check(data).Accepted
+}
+
+// Check is like Validate but returns the structured result of the validation.
+// Results are logged through the validation logger of the state.
+func (v *MutatingState) Check(data []byte) Result {
+	var result Result
+	envelope, err := v.state.codec.ParseEnvelope(data)
+	if err != nil {
+		result = reject(v.state.codec.Kind(data), Unparseable)
+		result.Err = err
+	} else {
+		v.wallet = &envelope.Wallet
+		result = envelope.Action.Validate(v)
+		v.wallet = nil
+	}
+	v.state.validationLog.log(result)
+	return result
 }
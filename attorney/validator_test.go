@@ -0,0 +1,118 @@
+package attorney
+
+import (
+	"testing"
+)
+
+func TestJoinLimits(t *testing.T) {
+	config := DefaultConfig()
+	config.JoinsPerBlock = 2
+	config.JoinsPerWallet = 1
+	state := newTestState(t, config)
+	wallet, other := newMember(), newMember()
+
+	v := state.Validator(1)
+	if result := check(v, wallet.key, signedJoin(1, newMember(), "first")); !result.Accepted {
+		t.Fatalf("first join rejected: %v", result.Reason)
+	}
+	if result := check(v, wallet.key, signedJoin(1, newMember(), "second")); result.Reason != WalletJoinLimit {
+		t.Errorf("second join of the wallet: %v", result.Reason)
+	}
+	if result := check(v, other.key, signedJoin(1, newMember(), "third")); !result.Accepted {
+		t.Fatalf("join of another wallet rejected: %v", result.Reason)
+	}
+	if result := check(v, newMember().key, signedJoin(1, newMember(), "fourth")); result.Reason != BlockJoinLimit {
+		t.Errorf("join over the block limit: %v", result.Reason)
+	}
+
+	// joins of an earlier block not yet incorporated do not count against
+	// the limits of the next one
+	next := state.Validator(2, v.Mutations())
+	if result := check(next, wallet.key, signedJoin(2, newMember(), "fifth")); !result.Accepted {
+		t.Errorf("join of the next block rejected: %v", result.Reason)
+	}
+	if result := check(next, other.key, signedJoin(2, newMember(), "sixth")); !result.Accepted {
+		t.Errorf("second join of the next block rejected: %v", result.Reason)
+	}
+}
+
+func signedLeave(epoch uint64, member testMember) *LeaveNetwork {
+	action := &LeaveNetwork{Epoch: epoch, Version: CurrentVersion, Author: member.token}
+	action.Sign(member.key)
+	return action
+}
+
+func TestFreeHandleClaimedAfterLeave(t *testing.T) {
+	config := DefaultConfig()
+	config.HandlePolicy = FreeHandle
+	state := newTestState(t, config)
+	first, second, third := newMember(), newMember(), newMember()
+	mustAccept(t, state, 1, first.key, signedJoin(1, first, "handle"))
+
+	v := state.Validator(2)
+	for _, action := range []Action{signedLeave(2, first), signedJoin(2, second, "handle")} {
+		if result := check(v, second.key, action); !result.Accepted {
+			t.Fatalf("kind %d rejected: %v", action.Kind(), result.Reason)
+		}
+	}
+	if token, ok := v.TokenOf("handle"); !ok || !token.Equal(second.token) {
+		t.Error("released handle not claimed within the block")
+	}
+	state.Incorporate(2, v.Mutations())
+	if token, ok := state.TokenOf("handle"); !ok || !token.Equal(second.token) {
+		t.Error("released handle not claimed")
+	}
+	if _, ok := state.HandleOf(first.token); ok {
+		t.Error("former member kept its handle")
+	}
+
+	// a member joining and leaving within a block releases its handle
+	v = state.Validator(3)
+	for _, action := range []Action{signedLeave(3, second), signedJoin(3, third, "handle"), signedLeave(3, third)} {
+		if result := check(v, third.key, action); !result.Accepted {
+			t.Fatalf("kind %d rejected: %v", action.Kind(), result.Reason)
+		}
+	}
+	if _, ok := v.TokenOf("handle"); ok || v.HasHandle("handle") {
+		t.Error("handle of a leaving member not released within the block")
+	}
+	state.Incorporate(3, v.Mutations())
+	if _, ok := state.TokenOf("handle"); ok || state.HasHandle("handle") {
+		t.Error("handle of a leaving member not released")
+	}
+	if _, ok := state.HandleOf(third.token); ok {
+		t.Error("member that left kept its handle")
+	}
+
+	// and the handle can be claimed by another member within the same block
+	fourth, fifth := newMember(), newMember()
+	v = state.Validator(4)
+	for _, action := range []Action{signedJoin(4, fourth, "other"), signedLeave(4, fourth), signedJoin(4, fifth, "other")} {
+		if result := check(v, fifth.key, action); !result.Accepted {
+			t.Fatalf("kind %d rejected: %v", action.Kind(), result.Reason)
+		}
+	}
+	if token, ok := v.TokenOf("other"); !ok || !token.Equal(fifth.token) {
+		t.Error("handle claimed after a leave within the block is ambiguous")
+	}
+	state.Incorporate(4, v.Mutations())
+	if token, ok := state.TokenOf("other"); !ok || !token.Equal(fifth.token) {
+		t.Error("handle claimed after a leave within the block indexed to the former member")
+	}
+}
+
+func TestTombstoneHandleAfterLeave(t *testing.T) {
+	state := newTestState(t, DefaultConfig())
+	first, second := newMember(), newMember()
+	mustAccept(t, state, 1, first.key, signedJoin(1, first, "handle"))
+	mustAccept(t, state, 2, first.key, signedLeave(2, first))
+	if !state.HasHandle("handle") {
+		t.Error("handle of a former member released")
+	}
+	if _, ok := state.TokenOf("handle"); ok {
+		t.Error("tombstoned handle still points to the former member")
+	}
+	if result := check(state.Validator(3), second.key, signedJoin(3, second, "handle")); result.Reason != HandleTaken {
+		t.Errorf("tombstoned handle claimed: %v", result.Reason)
+	}
+}
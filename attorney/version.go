@@ -0,0 +1,40 @@
+package attorney
+
+import (
+	"github.com/freehandle/breeze/protocol/actions"
+	"github.com/freehandle/breeze/util"
+)
+
+// Axé format versions. Version0 actions have the original layout, with the
+// kind byte right after the protocol code. Later versions set versionFlag on
+// the kind byte and follow it with the version byte, so nodes can tell the
+// layout of every action apart and older actions remain valid after an
+// upgrade. Axé actions keep the format version they were parsed with in their
+// Version field and are serialized in it.
+const (
+	Version0 byte = iota
+	// Version1 adds expiry, scope and delegation depth to grants of power of
+	// attorney.
+	Version1
+)
+
+// CurrentVersion is the latest axé format version understood by this package.
+const CurrentVersion = Version1
+
+// versionFlag marks kind bytes followed by a format version byte.
+const versionFlag byte = 0x80
+
+// putHeader starts the serialization of an axé action of the given kind in
+// the given format version for the axé network with the given protocol code.
+func putHeader(protocol [4]byte, epoch uint64, kind, version byte) []byte {
+	bytes := []byte{0, actions.IVoid} // breeze (version 0) void action
+	util.PutUint64(epoch, &bytes)
+	bytes = append(bytes, protocol[:]...)
+	if version == Version0 {
+		util.PutByte(kind, &bytes)
+	} else {
+		util.PutByte(kind|versionFlag, &bytes)
+		util.PutByte(version, &bytes)
+	}
+	return bytes
+}